@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"notioncli/utils"
 	"os"
@@ -11,22 +12,112 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add <task>",
 	Short: "Add a new task",
-	Long:  `Add a new task to the Notion ToDo task list page`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Add a new task to the Notion ToDo task list page.
+The text may use inline Markdown: **bold**, *italic*, ~~strikethrough~~, ` + "`code`" + ` and [label](url).
+Use --raw to add the text exactly as typed.
+
+New tasks go to the bottom of the page unless one of these says otherwise:
+  --top               put it above the first task
+  --after <task>      put it right after another task, given by number or ID
+  --parent <task>     add it as a subtask of another task
+  --section <heading> add it after the last task under that heading
+
+The Notion API cannot insert above the first block on a page. When the first task is that block,
+--top refuses unless --recreate is given as well: the first task is then recreated below the new one,
+so it gets a new ID and loses its created time and comments. Adding a heading above the tasks avoids this.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		text := args[0]
-		notionAPIKey, pageID := utils.SetAPIConfig()
-		result := utils.AddNewToDoItem(notionAPIKey, pageID, text)
-		if result != nil {
-			fmt.Printf("Error adding new task: %s\n", result)
-			os.Exit(1)
+		text := utils.ParseMarkdown(args[0])
+		if raw, _ := cmd.Flags().GetBool("raw"); raw {
+			text = utils.PlainRichText(args[0])
 		}
-		fmt.Printf("Task %s added.\n", text)
+		client, pageID := newNotionClient()
+		if _, err := addTask(cmd, client, pageID, text); err != nil {
+			exitWithError(err, "Error adding new task")
+		}
+		fmt.Printf("Task %s added.\n", utils.PlainText(text))
 
 	},
 }
 
+// addTask adds a to-do where the placement flags say.
+func addTask(cmd *cobra.Command, client *utils.NotionClient, pageID string, text []utils.RichText) (*utils.Block, error) {
+	ctx := cmd.Context()
+	parentRef, _ := cmd.Flags().GetString("parent")
+	sectionTitle, _ := cmd.Flags().GetString("section")
+	afterRef, _ := cmd.Flags().GetString("after")
+	top, _ := cmd.Flags().GetBool("top")
+	placements := 0
+	for _, given := range []bool{parentRef != "", sectionTitle != "", afterRef != "", top} {
+		if given {
+			placements++
+		}
+	}
+	if placements == 0 {
+		return client.AddToDo(ctx, pageID, text)
+	}
+	if placements > 1 {
+		fmt.Fprintln(os.Stderr, "only one of --top, --after, --parent and --section can be used")
+		os.Exit(exitUsage)
+	}
+
+	list, err := client.GetTaskList(ctx, pageID)
+	if err != nil {
+		exitWithError(err, "Error getting blocks from the pageID")
+	}
+	switch {
+	case parentRef != "":
+		parent := resolveRef(list, parentRef)
+		return client.AddToDo(ctx, parent.Block.ID, text)
+
+	case sectionTitle != "":
+		section, err := list.Section(sectionTitle)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		parentID, afterID := section.InsertionPoint()
+		return client.AddToDoAfter(ctx, parentID, afterID, text)
+
+	case afterRef != "":
+		task := resolveRef(list, afterRef)
+		parentID := task.Block.ParentID()
+		if parentID == "" {
+			parentID = pageID
+		}
+		return client.AddToDoAfter(ctx, parentID, task.Block.ID, text)
+	}
+
+	if len(list.Roots) == 0 {
+		return client.AddToDo(ctx, pageID, text)
+	}
+	recreate, _ := cmd.Flags().GetBool("recreate")
+	created, err := client.AddToDoBefore(ctx, list.Roots[0].Block, text, recreate)
+	if errors.Is(err, utils.ErrFirstChild) {
+		fmt.Fprintf(os.Stderr, "Task 1 is the first block on the page, and the Notion API cannot insert above it.\n"+
+			"Add --recreate to recreate task 1 below the new task, which gives it a new ID, or add a heading above the tasks.\n")
+		os.Exit(exitUsage)
+	}
+	return created, err
+}
+
+// resolveRef resolves a task number or ID given to a flag, exiting with a
+// usage error when there is no such task.
+func resolveRef(list *utils.TaskList, ref string) *utils.Task {
+	task, err := list.ResolveRef(ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	return task
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
-	checkCmd.Flags().String("text", "", "Text for the new task")
+	addCmd.Flags().String("parent", "", "number or ID of the task to add a subtask to")
+	addCmd.Flags().String("section", "", "heading of the section to add the task to")
+	addCmd.Flags().Bool("top", false, "add the task above the first task")
+	addCmd.Flags().Bool("recreate", false, "with --top, recreate the first task below the new one if it is the first block on the page")
+	addCmd.Flags().String("after", "", "number or ID of the task to add the new task after")
+	addCmd.Flags().Bool("raw", false, "add the text literally, without parsing Markdown")
 }
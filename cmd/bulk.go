@@ -0,0 +1,158 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"notioncli/utils"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// bulkAction describes what check, uncheck and delete do to each selected task.
+type bulkAction struct {
+	failure string // printf format taking the task number, e.g. "Error removing task %s"
+	success string // printf format taking the task number, e.g. "Task %s removed."
+	removes bool   // whether the action deletes the task
+	apply   func(ctx context.Context, client *utils.NotionClient, task *utils.Task) error
+}
+
+// setChecked returns a bulkAction.apply that checks or unchecks a task and
+// keeps the snapshot up to date with what Notion stored.
+func setChecked(checked bool) func(ctx context.Context, client *utils.NotionClient, task *utils.Task) error {
+	return func(ctx context.Context, client *utils.NotionClient, task *utils.Task) error {
+		updated, err := client.SetToDoChecked(ctx, task.Block, checked)
+		if err != nil {
+			return err
+		}
+		if updated.ID != "" {
+			task.Block = *updated
+		}
+		return nil
+	}
+}
+
+// addSelectorFlags registers the flags shared by commands that act on a set
+// of tasks.
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("all", false, "select every task")
+	cmd.Flags().Bool("done", false, "select completed tasks")
+	cmd.Flags().Bool("open", false, "select incomplete tasks")
+	cmd.Flags().String("match", "", "select tasks whose text matches this regular expression")
+	cmd.Flags().Bool("force", false, "act even if the page changed since you last listed it")
+}
+
+// selectorFromArgs builds a selector from positional expressions such as
+// "1,3,5-8,a1b2" and the selector flags.
+func selectorFromArgs(cmd *cobra.Command, args []string) (utils.Selector, error) {
+	var sel utils.Selector
+	if len(args) > 0 {
+		var err error
+		sel, err = utils.ParseSelector(strings.Join(args, ","))
+		if err != nil {
+			return sel, err
+		}
+	}
+	sel.All, _ = cmd.Flags().GetBool("all")
+	sel.Done, _ = cmd.Flags().GetBool("done")
+	sel.Open, _ = cmd.Flags().GetBool("open")
+	if sel.Done && sel.Open {
+		return sel, fmt.Errorf("--done and --open cannot be used together")
+	}
+	if pattern, _ := cmd.Flags().GetString("match"); pattern != "" {
+		match, err := regexp.Compile(pattern)
+		if err != nil {
+			return sel, fmt.Errorf("invalid --match expression: %v", err)
+		}
+		sel.Match = match
+	}
+	if sel.IsEmpty() {
+		return sel, fmt.Errorf("name the tasks to act on, e.g. 1,3,5-8 or a task ID, or use --all, --done, --open or --match")
+	}
+	return sel, nil
+}
+
+// runBulk resolves the selection against one snapshot of the page, applies
+// action to the selected tasks concurrently and reports the outcome of each.
+func runBulk(cmd *cobra.Command, args []string, action bulkAction) {
+	sel, err := selectorFromArgs(cmd, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+
+	ctx := cmd.Context()
+	client, pageID := newNotionClient()
+	list, err := client.GetTaskList(ctx, pageID)
+	if err != nil {
+		exitWithError(err, "Error getting blocks from the pageID")
+	}
+	tasks, err := list.Select(sel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("No tasks matched.")
+		return
+	}
+	selected := tasks
+	recursive, _ := cmd.Flags().GetBool("recursive")
+	if recursive {
+		tasks = utils.WithDescendants(tasks)
+	}
+
+	view := loadView(pageID)
+	if force, _ := cmd.Flags().GetBool("force"); !force {
+		err := view.CheckSelection(list, sel)
+		if err == nil && (recursive || action.removes) {
+			// Deleting a task deletes its subtasks too.
+			err = view.CheckDescendants(selected)
+		}
+		if err != nil {
+			exitWithError(err, "Refusing to continue")
+		}
+	}
+
+	results := utils.ForEachTask(ctx, tasks, utils.DefaultConcurrency, func(ctx context.Context, task *utils.Task) error {
+		return action.apply(ctx, client, task)
+	})
+
+	var firstErr error
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, action.failure+": %v\n", result.Task.Number, result.Err)
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			failed++
+			continue
+		}
+		fmt.Printf(action.success+"\n", result.Task.Number)
+		if view != nil {
+			if action.removes {
+				view.MarkDeleted(result.Task.Block.ID)
+			} else {
+				view.Record(result.Task)
+			}
+		}
+	}
+	saveView(view)
+
+	if len(tasks) > 1 {
+		fmt.Printf("%d of %d tasks succeeded.\n", len(tasks)-failed, len(tasks))
+	}
+	if firstErr != nil {
+		if hint := errorHint(firstErr); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
+		os.Exit(exitCode(firstErr))
+	}
+}
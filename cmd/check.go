@@ -5,37 +5,26 @@
 package cmd
 
 import (
-	"fmt"
-	"notioncli/utils"
-	"os"
-	"strconv"
-
 	"github.com/spf13/cobra"
 )
 
 var checkCmd = &cobra.Command{
-	Use:   "check <item order>",
-	Short: "Mark a task as complete",
-	Long:  `Mark a ToDo task as complete, e.g., check 1 (marks the first ToDo in the list complete)`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "check <tasks>...",
+	Short: "Mark tasks as complete",
+	Long: `Mark ToDo tasks as complete, e.g., check 1 (marks the first ToDo in the list complete).
+Several tasks can be given at once, e.g., check 1,3,5-8, or selected with --all, --open or --match <regex>.
+Subtasks are addressed by their number, e.g., check 3.1, and --recursive also completes every subtask of the selected tasks.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		order, err := strconv.Atoi(args[0])
-		if err != nil {
-			fmt.Printf("Could not convert %q to an integer: %v", args[0], err)
-			os.Exit(1)
-		}
-		notionAPIKey, pageID := utils.SetAPIConfig()
-		result := utils.MarkToDoBlockChecked(notionAPIKey, pageID, order)
-		if result != nil {
-			fmt.Printf("Error marking task %d as complete: %v\n", order, result)
-			os.Exit(1)
-		}
-		fmt.Printf("Task %d marked complete.\n", order)
-
+		runBulk(cmd, args, bulkAction{
+			failure: "Error marking task %s as complete",
+			success: "Task %s marked complete.",
+			apply:   setChecked(true),
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
-	checkCmd.Flags().Int("order", 0, "numeric order of the task to mark as complete")
+	addSelectorFlags(checkCmd)
+	checkCmd.Flags().Bool("recursive", false, "also complete every subtask of the selected tasks")
 }
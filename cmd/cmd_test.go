@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notioncli/notiontest"
+	"notioncli/utils"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// setupFake points the commands at a fresh fake Notion server through the
+// environment, the same way a user configures notioncli.
+func setupFake(t *testing.T) (*notiontest.Server, string) {
+	t.Helper()
+	server := notiontest.NewServer()
+	t.Cleanup(server.Close)
+	pageID := server.AddPage("Tasks")
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, ".env"), nil, 0o600); err != nil {
+		t.Fatalf("Error writing .env: %v", err)
+	}
+	workingDir, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Error changing directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(workingDir) })
+
+	t.Setenv("NOTION_API_KEY", "secret")
+	t.Setenv("NOTION_PAGE_ID", pageID)
+	t.Setenv("NOTION_API_URL", server.URL)
+	t.Setenv("LOCAL_TIMEZONE", "UTC")
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+	return server, pageID
+}
+
+// resetFlags restores every flag to its default, as cobra keeps flag values
+// between executions in the same process.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetFlags(child)
+	}
+}
+
+// run executes notioncli with args and returns what it printed to stdout.
+func run(t *testing.T, args ...string) string {
+	t.Helper()
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Error creating pipe: %v", err)
+	}
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		output <- string(data)
+	}()
+
+	err = rootCmd.ExecuteContext(context.Background())
+	w.Close()
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("notioncli %s: %v", strings.Join(args, " "), err)
+	}
+	return <-output
+}
+
+// TestMain lets runExit run a command that ends in os.Exit in a child
+// process: the child is this test binary with the arguments in the
+// environment.
+func TestMain(m *testing.M) {
+	if encoded := os.Getenv("NOTIONCLI_TEST_ARGS"); encoded != "" {
+		var args []string
+		if err := json.Unmarshal([]byte(encoded), &args); err != nil {
+			panic(err)
+		}
+		rootCmd.SetArgs(args)
+		if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+			os.Exit(exitUsage)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runExit executes notioncli with args in a child process, against the same
+// fake server and cache, and returns its exit code and what it printed to
+// stderr.
+func runExit(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Error finding the test binary: %v", err)
+	}
+	encoded, _ := json.Marshal(args)
+	child := exec.Command(executable)
+	child.Env = append(os.Environ(), "NOTIONCLI_TEST_ARGS="+string(encoded))
+	var stderr bytes.Buffer
+	child.Stderr = &stderr
+	err = child.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stderr.String()
+	}
+	if err != nil {
+		t.Fatalf("notioncli %s: %v", strings.Join(args, " "), err)
+	}
+	return 0, stderr.String()
+}
+
+func TestCommandsEndToEnd(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", false),
+		notiontest.ToDo("review PR", false),
+	)
+
+	run(t, "add", "ship it")
+	if out := run(t, "list"); !strings.Contains(out, "3 [ ] ship it") {
+		t.Errorf("Expected the new task to be listed third, got:\n%s", out)
+	}
+
+	out := run(t, "check", "1,3")
+	if !strings.Contains(out, "Task 1 marked complete.") || !strings.Contains(out, "Task 3 marked complete.") {
+		t.Errorf("Expected both tasks to be reported, got:\n%s", out)
+	}
+
+	run(t, "delete", "--done")
+	out = run(t, "list")
+	if strings.Contains(out, "write report") || strings.Contains(out, "ship it") || !strings.Contains(out, "1 [ ] review PR") {
+		t.Errorf("Expected only the open task to remain, got:\n%s", out)
+	}
+}
+
+func TestAddParsesMarkdown(t *testing.T) {
+	server, pageID := setupFake(t)
+
+	run(t, "add", "Review [PR 42](https://example.com/42) for **auth**")
+	run(t, "add", "--raw", "keep **stars**")
+
+	children := server.Children(pageID)
+	if len(children) != 2 {
+		t.Fatalf("Expected 2 tasks, got: %d", len(children))
+	}
+	formatted := children[0]["to_do"].(map[string]interface{})["rich_text"].([]interface{})
+	if len(formatted) != 4 {
+		t.Fatalf("Expected 4 segments, got: %v", formatted)
+	}
+	link := formatted[1].(map[string]interface{})["text"].(map[string]interface{})["link"].(map[string]interface{})
+	bold := formatted[3].(map[string]interface{})["annotations"].(map[string]interface{})
+	if link["url"] != "https://example.com/42" || bold["bold"] != true {
+		t.Errorf("Expected a link and bold text, got: %v", formatted)
+	}
+	literal := children[1]["to_do"].(map[string]interface{})["rich_text"].([]interface{})
+	if len(literal) != 1 || literal[0].(map[string]interface{})["plain_text"] != "keep **stars**" {
+		t.Errorf("Expected --raw to keep the text literal, got: %v", literal)
+	}
+}
+
+func TestSubtasks(t *testing.T) {
+	server, pageID := setupFake(t)
+	ids := server.AppendBlocks(pageID,
+		notiontest.ToDo("launch", false,
+			notiontest.ToDo("write post", false),
+		),
+	)
+
+	run(t, "add", "--parent", "1", "deploy")
+	out := run(t, "list")
+	if !strings.Contains(out, "1 [ ] launch") || !strings.Contains(out, "  1.1 [ ] write post") || !strings.Contains(out, "  1.2 [ ] deploy") {
+		t.Errorf("Expected an indented tree, got:\n%s", out)
+	}
+
+	out = run(t, "check", "1", "--recursive")
+	if !strings.Contains(out, "Task 1.2 marked complete.") || !strings.Contains(out, "3 of 3 tasks succeeded.") {
+		t.Errorf("Expected the task and both subtasks to be completed, got:\n%s", out)
+	}
+	for _, child := range server.Children(ids[0]) {
+		if child["to_do"].(map[string]interface{})["checked"] != true {
+			t.Errorf("Expected subtask %v to be checked", child["id"])
+		}
+	}
+}
+
+func TestTasksInsideContainers(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("top", false),
+		notiontest.Toggle("Errands", notiontest.ToDo("buy milk", false)),
+	)
+
+	if out := run(t, "list"); !strings.Contains(out, "2 [ ] buy milk in Errands") {
+		t.Errorf("Expected the task inside the toggle to be listed, got:\n%s", out)
+	}
+	run(t, "check", "2")
+	toggle := server.Children(pageID)[1]["id"].(string)
+	if server.Children(toggle)[0]["to_do"].(map[string]interface{})["checked"] != true {
+		t.Errorf("Expected the task inside the toggle to be checked")
+	}
+}
+
+func TestSectionsInListAndAdd(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", false),
+		notiontest.Paragraph("notes about today"),
+		notiontest.Heading(2, "Backlog"),
+		notiontest.ToDo("clean desk", false),
+	)
+
+	run(t, "add", "--section", "today", "review PR")
+	children := server.Children(pageID)
+	if text := children[2]["to_do"].(map[string]interface{})["rich_text"].([]interface{})[0].(map[string]interface{})["plain_text"]; text != "review PR" {
+		t.Errorf("Expected the task right after the last task of Today, got %v there", text)
+	}
+
+	out := run(t, "list")
+	if !strings.Contains(out, "Today:\n1 [ ] write report") || !strings.Contains(out, "\nBacklog:\n3 [ ] clean desk") {
+		t.Errorf("Expected tasks grouped under their headings, got:\n%s", out)
+	}
+	out = run(t, "list", "--section", "Backlog")
+	if strings.Contains(out, "write report") || !strings.Contains(out, "3 [ ] clean desk") {
+		t.Errorf("Expected only the Backlog section with its original numbers, got:\n%s", out)
+	}
+}
+
+func TestAddAtTopAndAfter(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("write report", false),
+		notiontest.ToDo("review PR", false),
+	)
+
+	if code, stderr := runExit(t, "add", "--top", "fix outage"); code != exitUsage || !strings.Contains(stderr, "--recreate") {
+		t.Errorf("Expected --top to refuse to recreate the first task, got exit code %d and:\n%s", code, stderr)
+	}
+	if out := run(t, "list"); strings.Contains(out, "fix outage") {
+		t.Errorf("Expected nothing added, got:\n%s", out)
+	}
+	run(t, "add", "--top", "--recreate", "fix outage")
+	run(t, "add", "--after", "2", "reply to email")
+	out := run(t, "list")
+	previous := -1
+	for i, want := range []string{"1 [ ] fix outage", "2 [ ] write report", "3 [ ] reply to email", "4 [ ] review PR"} {
+		at := strings.Index(out, want)
+		if at < previous {
+			t.Errorf("Expected %q as task %d, got:\n%s", want, i+1, out)
+		}
+		previous = at
+	}
+}
+
+func TestMoveCommand(t *testing.T) {
+	server, pageID := setupFake(t)
+	otherID := server.AddPage("Someday")
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("write report", false),
+		notiontest.ToDo("review PR", false),
+		notiontest.ToDo("learn Rust", false, notiontest.ToDo("read the book", false)),
+	)
+
+	run(t, "list")
+	if out := run(t, "move", "2", "--to", "1"); !strings.Contains(out, "Task 2 moved to position 1.") {
+		t.Errorf("Expected the move to be reported, got:\n%s", out)
+	}
+	run(t, "list")
+	run(t, "move", "3", "--page", "https://www.notion.so/Someday-"+strings.ReplaceAll(otherID, "-", ""))
+
+	out := run(t, "list")
+	if !strings.Contains(out, "1 [ ] review PR") || !strings.Contains(out, "2 [ ] write report") || strings.Contains(out, "learn Rust") {
+		t.Errorf("Expected the reordered tasks without the moved one, got:\n%s", out)
+	}
+	moved := server.Children(otherID)
+	if len(moved) != 1 || len(server.Children(moved[0]["id"].(string))) != 1 {
+		t.Errorf("Expected the task and its subtask on the other page, got: %v", moved)
+	}
+}
+
+func TestEditCommand(t *testing.T) {
+	server, pageID := setupFake(t)
+	ids := server.AppendBlocks(pageID,
+		notiontest.ToDo("wirte report", true, notiontest.ToDo("outline", false)),
+	)
+
+	run(t, "edit", "1", "write **report**")
+	task := server.Block(ids[0])
+	payload := task["to_do"].(map[string]interface{})
+	if payload["checked"] != true || len(payload["rich_text"].([]interface{})) != 2 || len(server.Children(ids[0])) != 1 {
+		t.Errorf("Expected new text with the checked state and subtask kept, got: %v", task)
+	}
+
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\ngrep -q 'write \\*\\*report\\*\\*' \"$1\" && printf 'write the *final* report\\n' > \"$1\"\n"
+	if err := ioutil.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatalf("Error writing editor: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+	run(t, "edit", "1")
+	if out := run(t, "list"); !strings.Contains(out, "1 [X] write the final report") {
+		t.Errorf("Expected the text saved in the editor, got:\n%s", out)
+	}
+}
+
+func TestEditAllCommand(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID, notiontest.ToDo("a", false), notiontest.ToDo("b", false))
+
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nsed -e 's/\\[ \\] b/[x] b/' -e '/\\] a </d' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\" && echo '- [ ] c' >> \"$1\"\n"
+	if err := ioutil.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatalf("Error writing editor: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+	out := run(t, "edit-all", "--yes")
+	for _, change := range []string{`check   2 "b"`, `add     "c" after 2 "b"`, `delete  1 "a"`, "Applied 3 changes."} {
+		if !strings.Contains(out, change) {
+			t.Errorf("Expected %q in the output, got:\n%s", change, out)
+		}
+	}
+	list := run(t, "list")
+	if !strings.Contains(list, "1 [X] b") || !strings.Contains(list, "2 [ ] c") || strings.Contains(list, "] a") {
+		t.Errorf("Expected the edited tasks, got:\n%s", list)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("write report", false),
+		notiontest.ToDo("review PR", true),
+		notiontest.ToDo("file expenses", false),
+	)
+
+	out := run(t, "list", "--open", "--grep", "re(port|view)", "--edited-since", "24h")
+	if !strings.Contains(out, "1 [ ] write report") || strings.Contains(out, "review PR") || strings.Contains(out, "expenses") {
+		t.Errorf("Expected only the open task matching the pattern, got:\n%s", out)
+	}
+	if out := run(t, "list", "--edited-before", "2000-01-01"); out != "" {
+		t.Errorf("Expected no tasks edited before 2000, got:\n%s", out)
+	}
+}
+
+func TestListSorting(t *testing.T) {
+	server, pageID := setupFake(t)
+	server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", false, notiontest.ToDo("outline", false)),
+		notiontest.ToDo("answer email", false),
+	)
+
+	out := run(t, "list", "--sort", "text", "--limit", "2")
+	first, second := strings.Index(out, "2 [ ] answer email"), strings.Index(out, "1.1 [ ] outline")
+	if first < 0 || second < first || strings.Contains(out, "write report") || strings.Contains(out, "Today:") {
+		t.Errorf("Expected the first two tasks alphabetically, flat and with their numbers, got:\n%s", out)
+	}
+	out = run(t, "list", "--sort", "text", "--reverse", "--limit", "1")
+	if !strings.HasPrefix(out, "1 [ ] write report") || strings.Count(out, "\n") != 1 {
+		t.Errorf("Expected only the last task alphabetically, got:\n%s", out)
+	}
+}
+
+func TestOutputFormatsAndShow(t *testing.T) {
+	server, pageID := setupFake(t)
+	ids := server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", true, notiontest.ToDo("outline", false)),
+	)
+
+	var records []map[string]interface{}
+	out := run(t, "list", "--output", "json")
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatalf("Expected JSON, got error %v for:\n%s", err, out)
+	}
+	if len(records) != 2 || records[0]["position"] != "1" || records[0]["id"] != ids[1] || records[1]["parent"] != ids[1] {
+		t.Errorf("Unexpected records: %v", records)
+	}
+
+	if out := run(t, "list", "-o", "markdown"); out != "- [x] write report\n  - [ ] outline\n" {
+		t.Errorf("Expected a Markdown checklist, got:\n%s", out)
+	}
+
+	out = run(t, "show", "1.1")
+	for _, want := range []string{"1.1 [ ] outline", "Parent:   1 write report", "Section:  Today", "URL:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in the details, got:\n%s", want, out)
+		}
+	}
+	if out := run(t, "show", "1", "--output", "ndjson"); strings.Count(out, "\n") != 1 || !strings.Contains(out, `"text":"write report"`) {
+		t.Errorf("Expected a single NDJSON record, got:\n%s", out)
+	}
+}
+
+func TestFilteredListOnlyVouchesForShownTasks(t *testing.T) {
+	server, pageID := setupFake(t)
+	ids := server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", false),
+		notiontest.Heading(2, "Backlog"),
+		notiontest.ToDo("clean desk", false),
+	)
+
+	run(t, "list")
+	run(t, "list", "--section", "Backlog")
+	code, stderr := runExit(t, "delete", "1")
+	if code != exitConflict || !strings.Contains(stderr, "was not shown") {
+		t.Errorf("Expected deleting a hidden task to be refused, got exit %d:\n%s", code, stderr)
+	}
+	if server.Block(ids[1])["archived"] == true {
+		t.Errorf("Expected the hidden task to survive")
+	}
+	if out := run(t, "delete", "2"); !strings.Contains(out, "Task 2 removed.") {
+		t.Errorf("Expected the shown task to be deleted, got:\n%s", out)
+	}
+}
+
+func TestRecursiveChangesCheckSubtasks(t *testing.T) {
+	server, pageID := setupFake(t)
+	ids := server.AppendBlocks(pageID, notiontest.ToDo("launch", false, notiontest.ToDo("write post", false)))
+
+	run(t, "list")
+	client, _ := newNotionClient()
+	list, err := client.GetTaskList(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if _, err := client.SetToDoText(context.Background(), list.Tasks[1].Block, utils.PlainRichText("write the post")); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	for _, args := range [][]string{{"check", "1", "--recursive"}, {"delete", "1"}} {
+		code, stderr := runExit(t, args...)
+		if code != exitConflict || !strings.Contains(stderr, "task 1.1 was") {
+			t.Errorf("%v: expected the edited subtask to be refused, got exit %d:\n%s", args, code, stderr)
+		}
+	}
+	if server.Block(ids[0])["archived"] == true {
+		t.Errorf("Expected the task to survive")
+	}
+
+	run(t, "list")
+	server.AppendBlocks(ids[0], notiontest.ToDo("deploy", false))
+	if code, stderr := runExit(t, "delete", "1"); code != exitConflict || !strings.Contains(stderr, "task 1.2 was not shown") {
+		t.Errorf("Expected a subtask added since list to be refused, got exit %d:\n%s", code, stderr)
+	}
+	if out := run(t, "check", "1"); !strings.Contains(out, "Task 1 marked complete.") {
+		t.Errorf("Expected checking only the task itself to go ahead, got:\n%s", out)
+	}
+}
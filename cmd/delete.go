@@ -5,37 +5,30 @@
 package cmd
 
 import (
-	"fmt"
+	"context"
 	"notioncli/utils"
-	"os"
-	"strconv"
 
 	"github.com/spf13/cobra"
 )
 
 var deleteCmd = &cobra.Command{
-	Use:   "delete <item order>",
-	Short: "Remove a task from the task list",
-	Long:  `Completely delete a task, e.g., delete 2 (removes the second task)`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "delete <tasks>...",
+	Short: "Remove tasks from the task list",
+	Long: `Completely delete a task, e.g., delete 2 (removes the second task).
+Several tasks can be given at once, e.g., delete 1,3,5-8, or selected with --all, --done, --open or --match <regex>.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		order, err := strconv.Atoi(args[0])
-		if err != nil {
-			fmt.Printf("Could not convert %q to an integer: %v", args[0], err)
-			os.Exit(1)
-		}
-		notionAPIKey, pageID := utils.SetAPIConfig()
-		result := utils.DeleteToDoBlock(notionAPIKey, pageID, order)
-		if result != nil {
-			fmt.Printf("Error removing task %d : %v\n", order, result)
-			os.Exit(1)
-		}
-		fmt.Printf("Task %d removed.\n", order)
-
+		runBulk(cmd, args, bulkAction{
+			failure: "Error removing task %s",
+			success: "Task %s removed.",
+			removes: true,
+			apply: func(ctx context.Context, client *utils.NotionClient, task *utils.Task) error {
+				return client.DeleteToDo(ctx, task.Block)
+			},
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
-	deleteCmd.Flags().Int("order", 0, "numeric order of the task to delete")
+	addSelectorFlags(deleteCmd)
 }
@@ -0,0 +1,97 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"notioncli/utils"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <task> [text]",
+	Short: "Change the text of a task",
+	Long: `Replace the text of a task, given by number or ID, keeping its position, checked state, colour and subtasks,
+e.g., edit 2 "Review **PR 42**". The text may use the same inline Markdown as add, or be taken literally with --raw.
+Without new text, the task opens in $EDITOR as Markdown and whatever you save is applied.
+Formatting that Markdown cannot express, such as colours and mentions, is lost when the text is edited.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		sel := singleTaskSelector(args[0])
+		ctx := cmd.Context()
+		client, pageID := newNotionClient()
+		list, err := client.GetTaskList(ctx, pageID)
+		if err != nil {
+			exitWithError(err, "Error getting blocks from the pageID")
+		}
+		task := resolveRef(list, args[0])
+		view := loadView(pageID)
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			if err := view.CheckSelection(list, sel); err != nil {
+				exitWithError(err, "Refusing to continue")
+			}
+		}
+
+		var source string
+		if len(args) == 2 {
+			source = args[1]
+		} else {
+			current := utils.RenderMarkdown(task.Block.ToDo.RichText)
+			edited, err := openEditor(current+"\n", "notioncli-*.md")
+			if err != nil {
+				exitWithError(err, "Error editing task %s", task.Number)
+			}
+			source = strings.TrimSpace(edited)
+			if source == current {
+				fmt.Printf("No changes to task %s.\n", task.Number)
+				return
+			}
+		}
+		if strings.TrimSpace(source) == "" {
+			fmt.Fprintln(os.Stderr, "The new text is empty; use delete to remove a task.")
+			os.Exit(exitUsage)
+		}
+		text := utils.ParseMarkdown(source)
+		if raw, _ := cmd.Flags().GetBool("raw"); raw {
+			text = utils.PlainRichText(source)
+		}
+
+		updated, err := client.SetToDoText(ctx, task.Block, text)
+		if err != nil {
+			exitWithError(err, "Error editing task %s", task.Number)
+		}
+		if updated.ID != "" {
+			task.Block = *updated
+		}
+		if view != nil {
+			view.Record(task)
+			saveView(view)
+		}
+		fmt.Printf("Task %s updated.\n", task.Number)
+	},
+}
+
+// singleTaskSelector parses a reference to exactly one task, exiting with a
+// usage error otherwise. The selector lets the stale view check the task.
+func singleTaskSelector(ref string) utils.Selector {
+	sel, err := utils.ParseSelector(ref)
+	if err == nil && len(sel.Positions)+len(sel.Numbers)+len(sel.IDs) != 1 {
+		err = fmt.Errorf("expected a single task, not %q", ref)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	return sel
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+	editCmd.Flags().Bool("raw", false, "use the text literally, without parsing Markdown")
+	editCmd.Flags().Bool("force", false, "act even if the page changed since you last listed it")
+}
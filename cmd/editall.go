@@ -0,0 +1,126 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"notioncli/utils"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var editAllCmd = &cobra.Command{
+	Use:   "edit-all",
+	Short: "Edit all tasks at once in $EDITOR",
+	Long: `Open the page's tasks in $EDITOR as a Markdown checklist. Edit text, tick or untick boxes, reorder lines,
+delete lines and add new "- [ ] text" lines, then save and close the editor.
+The changes are turned into the fewest Notion operations that apply them and shown as a plan to confirm;
+--yes applies them without asking. Each existing task ends in a hidden id comment that must be kept.
+Only to-dos placed directly on the page are listed; subtasks move with their parent task.
+The plan refers to tasks by the numbers list shows.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		client, pageID := newNotionClient()
+		children, err := client.GetTaskTree(ctx, pageID)
+		if err != nil {
+			exitWithError(err, "Error getting blocks from the pageID")
+		}
+
+		edited, err := openEditor(utils.RenderEditFile(utils.EditableTasks(pageID, children)), "notioncli-*.md")
+		if err != nil {
+			exitWithError(err, "Error editing tasks")
+		}
+		entries, err := utils.ParseEditFile(edited)
+		var plan *utils.EditPlan
+		if err == nil {
+			plan, err = utils.PlanEdits(pageID, children, entries)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Nothing changed: %v\n", err)
+			if path, err := keepEdits(edited); err == nil {
+				fmt.Fprintf(os.Stderr, "Your edits are saved in %s.\n", path)
+			}
+			os.Exit(exitUsage)
+		}
+		applyEditPlan(cmd, client, pageID, children, plan)
+	},
+}
+
+func applyEditPlan(cmd *cobra.Command, client *utils.NotionClient, pageID string, children []utils.Block, plan *utils.EditPlan) {
+	changes := plan.Describe()
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return
+	}
+	fmt.Println("Planned changes:")
+	for _, change := range changes {
+		fmt.Println("  " + change)
+	}
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes && !confirm(fmt.Sprintf("Apply %d changes?", len(changes))) {
+		fmt.Println("Nothing changed.")
+		return
+	}
+
+	ctx := cmd.Context()
+	if force, _ := cmd.Flags().GetBool("force"); !force {
+		current, err := client.GetAllBlockChildren(ctx, pageID)
+		if err == nil {
+			err = utils.CheckUnedited(pageID, children, current)
+		}
+		if err != nil {
+			exitWithError(err, "Refusing to continue")
+		}
+	}
+	if err := client.ApplyEdits(ctx, plan); err != nil {
+		exitWithError(err, "Error applying changes, stopping partway")
+	}
+
+	if view := loadView(pageID); view != nil {
+		if plan.Renumbers() {
+			for _, entry := range view.Tasks {
+				view.MarkDeleted(entry.ID)
+			}
+		} else {
+			for _, task := range plan.Updated() {
+				view.Record(task)
+			}
+		}
+		saveView(view)
+	}
+	fmt.Printf("Applied %d changes.\n", len(changes))
+}
+
+// confirm asks a yes/no question on the terminal; anything but yes is no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// keepEdits saves an edit-all file that could not be applied, so the user
+// does not lose their work.
+func keepEdits(content string) (string, error) {
+	file, err := ioutil.TempFile("", "notioncli-edit-all-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(editAllCmd)
+	editAllCmd.Flags().BoolP("yes", "y", false, "apply the changes without asking")
+	editAllCmd.Flags().Bool("force", false, "apply the changes even if the tasks were edited while the editor was open")
+}
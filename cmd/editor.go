@@ -0,0 +1,54 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// openEditor writes content to a temporary file named after pattern, opens
+// it in the user's editor ($VISUAL, then $EDITOR, then vi) and returns the
+// saved contents.
+func openEditor(content, pattern string) (string, error) {
+	file, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may come with arguments, as in EDITOR="code --wait".
+	args := strings.Fields(editor)
+	editorCmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	if err := editorCmd.Run(); err != nil {
+		return "", fmt.Errorf("error running %s: %v", editor, err)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
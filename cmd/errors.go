@@ -0,0 +1,113 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"notioncli/utils"
+	"os"
+)
+
+// Process exit codes, documented in the README so scripts can branch on them.
+const (
+	exitError        = 1
+	exitUsage        = 2
+	exitUnauthorized = 3
+	exitNotFound     = 4
+	exitValidation   = 5
+	exitConflict     = 6
+	exitRateLimited  = 7
+	exitUnavailable  = 8
+	exitTimeout      = 9
+	exitInterrupted  = 130
+)
+
+// exitCode maps an error to the process exit code for it.
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	if isTimeout(err) {
+		return exitTimeout
+	}
+	var staleErr *utils.StaleViewError
+	if errors.As(err, &staleErr) {
+		return exitConflict
+	}
+	var apiErr *utils.APIError
+	if !errors.As(err, &apiErr) {
+		return exitError
+	}
+	switch apiErr.Code {
+	case utils.CodeUnauthorized, utils.CodeRestrictedResource:
+		return exitUnauthorized
+	case utils.CodeObjectNotFound:
+		return exitNotFound
+	case utils.CodeValidationError, utils.CodeInvalidJSON, utils.CodeInvalidRequest, utils.CodeInvalidRequestURL, utils.CodeMissingVersion:
+		return exitValidation
+	case utils.CodeConflictError:
+		return exitConflict
+	case utils.CodeRateLimited:
+		return exitRateLimited
+	case utils.CodeInternalServerError, utils.CodeServiceUnavailable, utils.CodeDatabaseConnectionUnavailable, utils.CodeGatewayTimeout:
+		return exitUnavailable
+	}
+	return exitError
+}
+
+// errorHint suggests what the user can do about an error, or returns "".
+func errorHint(err error) string {
+	if isTimeout(err) {
+		return "The command ran out of time. Raise the limit with --timeout, or check your network connection."
+	}
+	var staleErr *utils.StaleViewError
+	if errors.As(err, &staleErr) {
+		return "Run `notioncli list` to see the current tasks, or pass --force to act anyway."
+	}
+	var apiErr *utils.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch apiErr.Code {
+	case utils.CodeUnauthorized:
+		return "Check that NOTION_API_KEY holds a valid integration token."
+	case utils.CodeRestrictedResource:
+		return "Your integration lacks the capability for this action; check its settings at https://www.notion.so/my-integrations."
+	case utils.CodeObjectNotFound:
+		return "The page isn't shared with your integration, or NOTION_PAGE_ID is wrong. Open the page in Notion and add your integration under Connections."
+	case utils.CodeValidationError, utils.CodeInvalidRequest:
+		return "Notion rejected the request; the task text may be too long or contain unsupported content."
+	case utils.CodeConflictError:
+		return "Someone else changed the page at the same time. Run `notioncli list` and try again."
+	case utils.CodeRateLimited:
+		return "Notion is rate limiting this integration. Wait a moment and try again."
+	case utils.CodeInternalServerError, utils.CodeServiceUnavailable, utils.CodeDatabaseConnectionUnavailable, utils.CodeGatewayTimeout:
+		return "Notion is having trouble right now. Try again shortly, or check https://status.notion.so."
+	}
+	return ""
+}
+
+// isTimeout reports whether err came from the --timeout deadline or an HTTP
+// request timing out.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// exitWithError prints a message for err, along with a hint when there is
+// one, and exits with the code that matches the error.
+func exitWithError(err error, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+": %v\n", append(args, err)...)
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+	os.Exit(exitCode(err))
+}
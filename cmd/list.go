@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"notioncli/utils"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -16,27 +19,197 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all tasks",
-	Long:  `List all tasks in the Notion page`,
+	Long: `List all tasks in the Notion page.
+Subtasks nested under a task are indented and numbered within it, e.g. 3.1 and 3.2.
+Tasks inside toggles, columns and synced blocks are listed in page order and show where they live.
+Tasks are grouped under the heading above them; --section <heading> lists only that section.
+With --ids, each task also shows a short ID that check, uncheck and delete accept in place of its number.
+
+Filters can be combined, and matching tasks keep their usual numbers:
+  --open / --done               only unchecked or checked tasks
+  --grep <regex>                tasks whose text matches, e.g. --grep '(?i)report'
+  --edited-since <time>         tasks edited at or after a time, e.g. 24h, 7d, today or 2023-05-01
+  --edited-before <time>        tasks last edited before a time
+  --created-since <time>        tasks created at or after a time
+  --color <colour>              tasks with that colour on the task or part of its text, or default for none
+Dates and times without an offset are in LOCAL_TIMEZONE.
+
+--sort edited|created|text|status orders the tasks: most recently edited or created first, alphabetically,
+or open before done; --reverse flips the order. Sorted tasks are listed without sections or indentation and
+keep their usual numbers, so they can still be passed to check, uncheck and delete.
+--limit N shows only the first N tasks.
+
+--output json|ndjson|csv|tsv|yaml|markdown prints the tasks as records for scripts instead; see the README for the fields.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		notionAPIKey, pageID := utils.SetAPIConfig()
+		client, pageID := newNotionClient()
 		localTimezone, err := utils.GetLocalTimeZone()
-		brightWhite := color.New(color.FgHiWhite).SprintFunc()
 		if err != nil {
 			fmt.Println("Error getting the local time zone: ", err)
 			os.Exit(1)
 		}
-		blocks, err := utils.GetToDoBlocks(notionAPIKey, pageID, localTimezone)
+		list, err := client.GetTaskList(cmd.Context(), pageID)
 		if err != nil {
-			fmt.Println("Error getting blocks from the pageID: ", err)
+			exitWithError(err, "Error getting blocks from the pageID")
+		}
 
-			os.Exit(1)
+		tasks := list.Tasks
+		if title, _ := cmd.Flags().GetString("section"); title != "" {
+			section, err := list.Section(title)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(exitUsage)
+			}
+			tasks = section.Tasks
 		}
-		for _, block := range blocks {
-			fmt.Println(brightWhite(block))
+		tasks = listFilter(cmd, localTimezone).Filter(tasks)
+		sortKey, _ := cmd.Flags().GetString("sort")
+		if reverse, _ := cmd.Flags().GetBool("reverse"); sortKey != "" {
+			tasks, err = utils.SortTasks(tasks, sortKey, reverse)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(exitUsage)
+			}
+		} else if reverse {
+			fmt.Fprintln(os.Stderr, "--reverse needs --sort")
+			os.Exit(exitUsage)
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		if limit < 0 {
+			fmt.Fprintln(os.Stderr, "--limit must not be negative")
+			os.Exit(exitUsage)
+		}
+		if limit > 0 && limit < len(tasks) {
+			tasks = tasks[:limit]
+		}
+		if outputFormat != "text" {
+			printRecords(pageID, tasks)
+			saveView(utils.NewView(pageID, tasks))
+			return
+		}
+
+		showIDs, _ := cmd.Flags().GetBool("ids")
+		var shortIDs map[*utils.Task]string
+		if showIDs {
+			shortIDs = list.ShortIDs()
 		}
+		sectionTitle := color.New(color.FgHiWhite, color.Bold).SprintFunc()
+		var section *utils.Section
+		for i, task := range tasks {
+			// Sorted tasks are no longer next to their section or parent, so
+			// they are listed flat; the numbers still show where they live.
+			if task.Section != section && sortKey == "" {
+				section = task.Section
+				if i > 0 {
+					fmt.Println()
+				}
+				if section != nil {
+					fmt.Println(sectionTitle(section.Title + ":"))
+				}
+			}
+			line, err := formatTask(task, shortIDs[task], sortKey == "", localTimezone)
+			if err != nil {
+				exitWithError(err, "Error formatting task %s", task.Number)
+			}
+			fmt.Println(line)
+		}
+		saveView(utils.NewView(pageID, tasks))
 	},
 }
 
+// listFilter builds the task filter from list's flags, exiting with a usage
+// error if one is invalid.
+func listFilter(cmd *cobra.Command, localTimezone *time.Location) utils.TaskFilter {
+	var filter utils.TaskFilter
+	var err error
+	flags := cmd.Flags()
+	filter.Open, _ = flags.GetBool("open")
+	filter.Done, _ = flags.GetBool("done")
+	if filter.Open && filter.Done {
+		err = fmt.Errorf("only one of --open and --done can be used")
+	}
+	if pattern, _ := flags.GetString("grep"); pattern != "" && err == nil {
+		filter.Pattern, err = regexp.Compile(pattern)
+	}
+	if colour, _ := flags.GetString("color"); colour != "" && err == nil {
+		filter.Color, err = utils.ParseColor(colour)
+	}
+	now := time.Now()
+	for _, bound := range []struct {
+		flag string
+		time *time.Time
+	}{
+		{"edited-since", &filter.EditedSince},
+		{"edited-before", &filter.EditedBefore},
+		{"created-since", &filter.CreatedSince},
+	} {
+		if value, _ := flags.GetString(bound.flag); value != "" && err == nil {
+			*bound.time, err = utils.ParseTime(value, now, localTimezone)
+			if err != nil {
+				err = fmt.Errorf("--%s: %v", bound.flag, err)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	return filter
+}
+
+// formatTask renders a task as a single `list` line, e.g.
+// "1 [X] text (2023-05-01 10:00)", with its short ID after the number when
+// one is given. With indent, subtasks are indented under their parent. Tasks
+// inside toggles, columns or synced blocks name their container after the
+// text. The text keeps its Notion formatting when stdout is a terminal.
+func formatTask(task *utils.Task, shortID string, indent bool, localTimezone *time.Location) (string, error) {
+	block := task.Block
+	checked := " "
+	if block.ToDo.Checked {
+		checked = "X"
+	}
+	lastEditedTime, err := time.Parse(time.RFC3339, block.LastEditedTime)
+	if err != nil {
+		return "", err
+	}
+	truncatedTime := lastEditedTime.In(localTimezone).Truncate(time.Minute)
+
+	brightWhite := color.New(color.FgHiWhite).SprintFunc()
+	number := task.Number
+	if indent {
+		number = strings.Repeat("  ", task.Depth()) + number
+	}
+	if shortID != "" {
+		number += " #" + shortID
+	}
+	text := utils.RenderRichText(block.ToDo.RichText, color.FgHiWhite)
+	if location := taskLocation(task); location != "" {
+		text += color.New(color.FgHiBlack).Sprint(" in " + location)
+	}
+	return brightWhite(fmt.Sprintf("%s [%s] ", number, checked)) + text + brightWhite(fmt.Sprintf(" (%s)", truncatedTime.Format("2006-01-02 15:04"))), nil
+}
+
+// taskLocation describes where a task lives when that differs from where
+// its parent lives, e.g. "Errands › column 2".
+func taskLocation(task *utils.Task) string {
+	location := strings.Join(task.Location, " › ")
+	if task.Parent != nil && location == strings.Join(task.Parent.Location, " › ") {
+		return ""
+	}
+	return location
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().Bool("ids", false, "show a short, stable ID for each task")
+	listCmd.Flags().String("section", "", "only list the tasks under this heading")
+	listCmd.Flags().Bool("open", false, "only list unchecked tasks")
+	listCmd.Flags().Bool("done", false, "only list checked tasks")
+	listCmd.Flags().String("grep", "", "only list tasks whose text matches this regular expression")
+	listCmd.Flags().String("edited-since", "", "only list tasks edited at or after this time")
+	listCmd.Flags().String("edited-before", "", "only list tasks last edited before this time")
+	listCmd.Flags().String("created-since", "", "only list tasks created at or after this time")
+	listCmd.Flags().String("color", "", "only list tasks with this Notion colour")
+	listCmd.Flags().String("sort", "", "order tasks by edited, created, text or status")
+	listCmd.Flags().Bool("reverse", false, "reverse the --sort order")
+	listCmd.Flags().Int("limit", 0, "show at most this many tasks")
 }
@@ -0,0 +1,73 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"notioncli/utils"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move <task>",
+	Short: "Move a task to another position or page",
+	Long: `Move a task, given by number or ID, together with its subtasks.
+Use --to <position> to reorder it, e.g., move 5 --to 1 makes task 5 the first task. Subtasks move among their siblings.
+Use --page <page ID or URL> to move it to the end of another task list.
+
+Notion cannot move blocks, so the task is recreated in its new place and the original is then deleted;
+the moved task gets a new ID. If a step fails, the changes made so far are undone as far as possible;
+if something is left behind, such as a duplicate the undo could not delete, the error says what.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		to, _ := cmd.Flags().GetInt("to")
+		page, _ := cmd.Flags().GetString("page")
+		if (to == 0) == (page == "") {
+			fmt.Fprintln(os.Stderr, "give either --to <position> or --page <page>")
+			os.Exit(exitUsage)
+		}
+		sel := singleTaskSelector(args[0])
+
+		ctx := cmd.Context()
+		client, pageID := newNotionClient()
+		list, err := client.GetTaskList(ctx, pageID)
+		if err != nil {
+			exitWithError(err, "Error getting blocks from the pageID")
+		}
+		task := resolveRef(list, args[0])
+		view := loadView(pageID)
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			if err := view.CheckSelection(list, sel); err != nil {
+				exitWithError(err, "Refusing to continue")
+			}
+		}
+
+		destination := fmt.Sprintf("position %d", to)
+		if page != "" {
+			destination = "page " + page
+			_, err = client.MoveBlock(ctx, task.Block, utils.ParsePageID(page), "")
+		} else {
+			_, err = client.MoveTask(ctx, list, task, to)
+		}
+		if err != nil {
+			exitWithError(err, "Error moving task %s", task.Number)
+		}
+		// Every number after the old and new places has shifted.
+		if view != nil {
+			view.MarkDeleted(task.Block.ID)
+			saveView(view)
+		}
+		fmt.Printf("Task %s moved to %s.\n", task.Number, destination)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+	moveCmd.Flags().Int("to", 0, "position to move the task to")
+	moveCmd.Flags().String("page", "", "ID or URL of the page to move the task to")
+	moveCmd.Flags().Bool("force", false, "act even if the page changed since you last listed it")
+}
@@ -0,0 +1,33 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"notioncli/utils"
+	"os"
+)
+
+func validOutputFormat(format string) bool {
+	if format == "text" {
+		return true
+	}
+	for _, known := range utils.OutputFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// printRecords writes tasks to stdout as records in the --output format.
+func printRecords(pageID string, tasks []*utils.Task) {
+	records := make([]utils.TaskRecord, 0, len(tasks))
+	for _, task := range tasks {
+		records = append(records, utils.NewTaskRecord(pageID, task))
+	}
+	if err := utils.WriteRecords(os.Stdout, outputFormat, records); err != nil {
+		exitWithError(err, "Error writing %s output", outputFormat)
+	}
+}
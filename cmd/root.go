@@ -5,8 +5,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"notioncli/utils"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -21,23 +27,83 @@ var rootCmd = &cobra.Command{
 		This version supports the following options:
 		  list (to list tasks)
 		  add <task> (create a new task)
-		  check <number> (mark a task done)
-		  uncheck <number> (mark a task as not done)
-		  delete <number> (permanently remove a task)
+		  check <numbers> (mark tasks done, e.g. 1,3,5-8 or --all)
+		  uncheck <numbers> (mark tasks as not done)
+		  delete <numbers> (permanently remove tasks)
+		  move <number> (reorder a task or move it to another page)
+		  edit <number> [text] (change a task's text, or open it in $EDITOR)
+		  edit-all (edit, reorder, add and remove tasks together in $EDITOR)
+		  show <numbers> (show the details of tasks)
 		  help (get some help)`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if !validOutputFormat(outputFormat) {
+			fmt.Fprintf(os.Stderr, "unknown output format %q; use text or one of %s\n", outputFormat, strings.Join(utils.OutputFormats, ", "))
+			os.Exit(exitUsage)
+		}
+		// The banner would corrupt machine-readable output.
+		if showBanner && outputFormat == "text" {
+			boldBlue := color.New(color.Bold, color.FgBlue).SprintFunc()
+			fmt.Println(boldBlue("----=[ NotionCLI ]=----"))
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			cancelTimeout = cancel
+			cmd.SetContext(ctx)
+		}
+	},
 }
 
+var (
+	timeout       time.Duration
+	cancelTimeout context.CancelFunc = func() {}
+	recordPath    string
+	replayPath    string
+	outputFormat  string
+	showBanner    bool
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	boldBlue := color.New(color.Bold, color.FgBlue).SprintFunc()
-	fmt.Println(boldBlue("----=[ NotionCLI ]=----"))
-	err := rootCmd.Execute()
+	showBanner = true
+	// Ctrl-C and SIGTERM cancel the context handed to every subcommand, which
+	// aborts any request in flight.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 }
 
-func init() {
+// newNotionClient loads the API configuration and returns a client for it
+// along with the configured task page ID. NOTION_API_URL, when set, points
+// the client at another API root such as a proxy or a notiontest server.
+func newNotionClient() (*utils.NotionClient, string) {
+	notionAPIKey, pageID := utils.SetAPIConfig()
+	var opts []utils.ClientOption
+	if baseURL := os.Getenv("NOTION_API_URL"); baseURL != "" {
+		opts = append(opts, utils.WithBaseURL(baseURL))
+	}
+	switch {
+	case replayPath != "":
+		replayer, err := utils.LoadReplayer(replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", replayPath, err)
+			os.Exit(exitUsage)
+		}
+		opts = append(opts, utils.WithTransport(replayer), utils.WithRateLimit(0, 0))
+	case recordPath != "":
+		opts = append(opts, utils.WithTransport(utils.NewRecorder(recordPath, nil)))
+	}
+	return utils.NewNotionClient(notionAPIKey, opts...), pageID
+}
 
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "abort the command if it takes longer than this, e.g. 30s (0 means no limit)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "print tasks from list and show as text, json, ndjson, csv, tsv, yaml or markdown")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record API traffic to this file, with the API key redacted, to attach to a bug report")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "answer API requests from a file written by --record instead of calling Notion")
+	rootCmd.PersistentFlags().MarkHidden("replay")
 }
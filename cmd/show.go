@@ -0,0 +1,104 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"notioncli/utils"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <tasks>",
+	Short: "Show the details of tasks",
+	Long: `Show everything notioncli knows about tasks given by number, range or ID, e.g., show 3 or show 1,4.1-4.3:
+their ID, a link to them in Notion, parent task, section, colour and when they were created and last edited.
+With --output, the tasks are printed as the same records list prints.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sel, err := utils.ParseSelector(strings.Join(args, ","))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		localTimezone, err := utils.GetLocalTimeZone()
+		if err != nil {
+			fmt.Println("Error getting the local time zone: ", err)
+			os.Exit(1)
+		}
+		client, pageID := newNotionClient()
+		list, err := client.GetTaskList(cmd.Context(), pageID)
+		if err != nil {
+			exitWithError(err, "Error getting blocks from the pageID")
+		}
+		tasks, err := list.Select(sel)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+
+		if outputFormat != "text" {
+			printRecords(pageID, tasks)
+			return
+		}
+		for i, task := range tasks {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(formatDetails(utils.NewTaskRecord(pageID, task), task, localTimezone))
+		}
+	},
+}
+
+// formatDetails renders a task for show: its list line without the time,
+// followed by one labelled field per line.
+func formatDetails(record utils.TaskRecord, task *utils.Task, localTimezone *time.Location) string {
+	checked := " "
+	if record.Checked {
+		checked = "X"
+	}
+	brightWhite := color.New(color.FgHiWhite).SprintFunc()
+	label := color.New(color.FgHiBlack).SprintfFunc()
+
+	var out strings.Builder
+	out.WriteString(brightWhite(fmt.Sprintf("%s [%s] ", record.Position, checked)) + utils.RenderRichText(record.RichText, color.FgHiWhite) + "\n")
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&out, "  %s %s\n", label("%-9s", name+":"), value)
+		}
+	}
+	field("ID", record.ID)
+	field("URL", record.URL)
+	if task.Parent != nil {
+		field("Parent", task.Parent.Number+" "+task.Parent.Block.ToDo.PlainText())
+	}
+	if task.Section != nil {
+		field("Section", task.Section.Title)
+	}
+	field("In", strings.Join(task.Location, " › "))
+	field("Color", record.Color)
+	field("Created", localTime(record.CreatedTime, localTimezone))
+	field("Edited", localTime(record.LastEditedTime, localTimezone))
+	return out.String()
+}
+
+// localTime formats a Notion timestamp the way list does, or returns it
+// unchanged if it cannot be parsed.
+func localTime(timestamp string, localTimezone *time.Location) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	return t.In(localTimezone).Format("2006-01-02 15:04")
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
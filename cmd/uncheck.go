@@ -5,36 +5,26 @@
 package cmd
 
 import (
-	"fmt"
-	"notioncli/utils"
-	"os"
-	"strconv"
-
 	"github.com/spf13/cobra"
 )
 
 var uncheckCmd = &cobra.Command{
-	Use:   "uncheck <item order>",
-	Short: "Mark a task as incomplete",
-	Long:  `Mark a ToDo task as incomplete, e.g., check 1 (marks the first ToDo in the list incomplete)`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "uncheck <tasks>...",
+	Short: "Mark tasks as incomplete",
+	Long: `Mark ToDo tasks as incomplete, e.g., uncheck 1 (marks the first ToDo in the list incomplete).
+Several tasks can be given at once, e.g., uncheck 1,3,5-8, or selected with --all, --done or --match <regex>.
+Subtasks are addressed by their number, e.g., uncheck 3.1, and --recursive also reopens every subtask of the selected tasks.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		order, err := strconv.Atoi(args[0])
-		if err != nil {
-			fmt.Printf("Could not convert %q to an integer: %v", args[0], err)
-			os.Exit(1)
-		}
-		notionAPIKey, pageID := utils.SetAPIConfig()
-		result := utils.MarkToDoBlockUnChecked(notionAPIKey, pageID, order)
-		if result != nil {
-			fmt.Printf("Error marking task %d as incomplete: %v\n", order, result)
-			os.Exit(1)
-		}
-		fmt.Printf("Task %d marked incomplete.\n", order)
+		runBulk(cmd, args, bulkAction{
+			failure: "Error marking task %s as incomplete",
+			success: "Task %s marked incomplete.",
+			apply:   setChecked(false),
+		})
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(uncheckCmd)
-	uncheckCmd.Flags().Int("order", 0, "numeric order of the task to mark as complete")
+	addSelectorFlags(uncheckCmd)
+	uncheckCmd.Flags().Bool("recursive", false, "also mark every subtask of the selected tasks incomplete")
 }
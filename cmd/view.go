@@ -0,0 +1,44 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"notioncli/utils"
+	"os"
+)
+
+// loadView returns the view `list` last saved for the page, or nil when
+// there is none or it cannot be read.
+func loadView(pageID string) *utils.View {
+	path, err := utils.ViewPath(pageID)
+	if err != nil {
+		return nil
+	}
+	view, err := utils.LoadView(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring the saved task list: %v\n", err)
+		return nil
+	}
+	if view != nil && view.PageID != pageID {
+		return nil
+	}
+	return view
+}
+
+// saveView stores the view for the next destructive command to check
+// against. Failing to save it only costs that protection, so it is a warning.
+func saveView(view *utils.View) {
+	if view == nil {
+		return
+	}
+	path, err := utils.ViewPath(view.PageID)
+	if err == nil {
+		err = view.Save(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not save the task list for stale-view protection: %v\n", err)
+	}
+}
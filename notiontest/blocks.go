@@ -0,0 +1,105 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package notiontest
+
+// Block builds a block of any type for AppendBlocks from its type payload,
+// e.g. Block("divider", nil).
+func Block(blockType string, payload map[string]interface{}, children ...map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	block := map[string]interface{}{
+		"object":  "block",
+		"type":    blockType,
+		blockType: payload,
+	}
+	if len(children) > 0 {
+		var nested []interface{}
+		for _, child := range children {
+			nested = append(nested, child)
+		}
+		block["children"] = nested
+	}
+	return block
+}
+
+// ToDo builds a to_do block with plain text.
+func ToDo(text string, checked bool, children ...map[string]interface{}) map[string]interface{} {
+	return Block("to_do", map[string]interface{}{"rich_text": richText(text), "checked": checked}, children...)
+}
+
+// Paragraph builds a paragraph block with plain text.
+func Paragraph(text string) map[string]interface{} {
+	return Block("paragraph", map[string]interface{}{"rich_text": richText(text)})
+}
+
+// Heading builds a heading_1, heading_2 or heading_3 block.
+func Heading(level int, text string) map[string]interface{} {
+	blockType := "heading_2"
+	switch level {
+	case 1:
+		blockType = "heading_1"
+	case 3:
+		blockType = "heading_3"
+	}
+	return Block(blockType, map[string]interface{}{"rich_text": richText(text)})
+}
+
+// Toggle builds a toggle block holding children.
+func Toggle(text string, children ...map[string]interface{}) map[string]interface{} {
+	return Block("toggle", map[string]interface{}{"rich_text": richText(text)}, children...)
+}
+
+func richText(text string) []interface{} {
+	segment := map[string]interface{}{"type": "text", "text": map[string]interface{}{"content": text}}
+	normalizeRichText(segment)
+	return []interface{}{segment}
+}
+
+// normalizePayload fills in the read-only fields Notion adds to a block
+// payload it stores, such as plain_text and default colours.
+func normalizePayload(payload map[string]interface{}) {
+	if segments, ok := payload["rich_text"].([]interface{}); ok {
+		for _, raw := range segments {
+			if segment, ok := raw.(map[string]interface{}); ok {
+				normalizeRichText(segment)
+			}
+		}
+	}
+	if _, ok := payload["rich_text"]; ok {
+		if _, ok := payload["color"]; !ok {
+			payload["color"] = "default"
+		}
+	}
+}
+
+func normalizeRichText(segment map[string]interface{}) {
+	if _, ok := segment["type"]; !ok {
+		segment["type"] = "text"
+	}
+	annotations, _ := segment["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	for _, name := range []string{"bold", "italic", "strikethrough", "underline", "code"} {
+		if _, ok := annotations[name]; !ok {
+			annotations[name] = false
+		}
+	}
+	if _, ok := annotations["color"]; !ok {
+		annotations["color"] = "default"
+	}
+	segment["annotations"] = annotations
+
+	segment["href"] = nil
+	if text, ok := segment["text"].(map[string]interface{}); ok {
+		segment["plain_text"] = text["content"]
+		if link, ok := text["link"].(map[string]interface{}); ok {
+			segment["href"] = link["url"]
+		} else {
+			text["link"] = nil
+		}
+	}
+}
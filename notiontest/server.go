@@ -0,0 +1,769 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+// Package notiontest provides an in-memory fake of the Notion API for tests.
+//
+// The fake keeps a real tree of pages, databases and blocks, and implements
+// the endpoints notioncli uses: block children (with pagination and the
+// `after` parameter), block retrieve/update/delete, page retrieve, search and
+// database query. Failures such as rate limiting can be injected per route.
+//
+//	server := notiontest.NewServer()
+//	defer server.Close()
+//	pageID := server.AddPage("Tasks")
+//	server.AppendBlocks(pageID, notiontest.ToDo("water the plants", false))
+//	client := utils.NewNotionClient("secret", utils.WithBaseURL(server.URL))
+package notiontest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxPageSize is the largest page_size the fake accepts, as in Notion.
+const MaxPageSize = 100
+
+// object is a page, database or block stored by the fake. data holds the
+// JSON object exactly as the API returns it, kept up to date on every change.
+type object struct {
+	id       string
+	kind     string // "page", "database" or "block"
+	parentID string
+	data     map[string]interface{}
+	children []string
+}
+
+// Failure makes the server answer matching requests with an error instead of
+// handling them. Method and Path are matched exactly when set; Path is the
+// request path without the query string, e.g. "/blocks/<id>/children".
+type Failure struct {
+	Method     string
+	Path       string
+	Status     int
+	Code       string
+	RetryAfter int // seconds, sent as the Retry-After header when set
+	Times      int // how many requests fail; zero means every request
+}
+
+// Server is an in-memory fake of the Notion API. It embeds the underlying
+// httptest.Server, so URL and Close are available directly.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	objects  map[string]*object
+	order    []string // creation order of pages and databases, for search
+	failures []*Failure
+	requests []string
+
+	// Now returns the time stamped on created and edited objects. Notion
+	// rounds these to the minute, and so does the fake.
+	Now func() time.Time
+}
+
+// NewServer starts a fake Notion API server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		objects: map[string]*object{},
+		Now:     time.Now,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func (s *Server) timestamp() string {
+	return s.Now().UTC().Truncate(time.Minute).Format("2006-01-02T15:04:05.000Z")
+}
+
+// AddPage creates a top-level page and returns its ID.
+func (s *Server) AddPage(title string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addPage(map[string]interface{}{"type": "workspace", "workspace": true}, "", title, nil)
+}
+
+// AddDatabase creates a top-level database and returns its ID.
+func (s *Server) AddDatabase(title string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := newID()
+	now := s.timestamp()
+	s.objects[id] = &object{id: id, kind: "database", data: map[string]interface{}{
+		"object":           "database",
+		"id":               id,
+		"created_time":     now,
+		"last_edited_time": now,
+		"title":            richText(title),
+		"parent":           map[string]interface{}{"type": "workspace", "workspace": true},
+		"properties":       map[string]interface{}{},
+		"archived":         false,
+		"url":              "https://www.notion.so/" + strings.ReplaceAll(id, "-", ""),
+	}}
+	s.order = append(s.order, id)
+	return id
+}
+
+// AddDatabasePage creates a page in a database and returns its ID. The title
+// becomes the page's "Name" property; extra properties are stored as given.
+func (s *Server) AddDatabasePage(databaseID, title string, properties map[string]interface{}) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parent := map[string]interface{}{"type": "database_id", "database_id": databaseID}
+	return s.addPage(parent, databaseID, title, properties)
+}
+
+func (s *Server) addPage(parent map[string]interface{}, parentID, title string, properties map[string]interface{}) string {
+	id := newID()
+	now := s.timestamp()
+	props := map[string]interface{}{}
+	for name, value := range properties {
+		props[name] = value
+	}
+	titleName := "title"
+	if parentID != "" {
+		titleName = "Name"
+	}
+	props[titleName] = map[string]interface{}{"id": "title", "type": "title", "title": richText(title)}
+	s.objects[id] = &object{id: id, kind: "page", parentID: parentID, data: map[string]interface{}{
+		"object":           "page",
+		"id":               id,
+		"created_time":     now,
+		"last_edited_time": now,
+		"parent":           parent,
+		"properties":       props,
+		"archived":         false,
+		"url":              "https://www.notion.so/" + strings.ReplaceAll(id, "-", ""),
+	}}
+	s.order = append(s.order, id)
+	return id
+}
+
+// AppendBlocks appends blocks, built with helpers such as ToDo or as raw
+// JSON-shaped maps, to the end of a page or block. Blocks may carry nested
+// "children". It returns the IDs of the top-level blocks it created.
+func (s *Server) AppendBlocks(parentID string, blocks ...map[string]interface{}) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parent, ok := s.objects[parentID]
+	if !ok {
+		panic("notiontest: no page or block with ID " + parentID)
+	}
+	created, err := s.insertChildren(parent, blocks, len(parent.children))
+	if err != nil {
+		panic("notiontest: " + err.Error())
+	}
+	var ids []string
+	for _, block := range created {
+		ids = append(ids, block["id"].(string))
+	}
+	return ids
+}
+
+// Block returns a copy of a block as the API would return it, or nil.
+func (s *Server) Block(id string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[id]
+	if !ok || obj.kind != "block" {
+		return nil
+	}
+	return clone(obj.data)
+}
+
+// Children returns copies of the unarchived children of a page or block, in
+// order.
+func (s *Server) Children(parentID string) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[parentID]
+	if !ok {
+		return nil
+	}
+	var children []map[string]interface{}
+	for _, child := range s.liveChildren(obj) {
+		children = append(children, clone(child.data))
+	}
+	return children
+}
+
+// Archive archives a block as if someone deleted it in the Notion app.
+func (s *Server) Archive(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if obj, ok := s.objects[id]; ok {
+		s.archive(obj)
+	}
+}
+
+// Fail registers an injected failure. Failures are checked in the order they
+// were added.
+func (s *Server) Fail(failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := failure
+	if f.Code == "" {
+		f.Code = codeForStatus(f.Status)
+	}
+	s.failures = append(s.failures, &f)
+}
+
+// Requests returns every request served so far as "METHOD /path?query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// RequestCount returns how many requests had the given method and path,
+// ignoring query strings.
+func (s *Server) RequestCount(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, request := range s.requests {
+		request = strings.SplitN(request, "?", 2)[0]
+		if request == method+" "+path {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	request := r.Method + " " + path
+	if r.URL.RawQuery != "" {
+		request += "?" + r.URL.RawQuery
+	}
+	s.requests = append(s.requests, request)
+
+	if f := s.takeFailure(r.Method, path); f != nil {
+		if f.RetryAfter > 0 || f.Status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+		}
+		writeError(w, f.Status, f.Code, "Injected failure.")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "API token is invalid.")
+		return
+	}
+	if r.Header.Get("Notion-Version") == "" {
+		writeError(w, http.StatusBadRequest, "missing_version", "Notion-Version header failed validation.")
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		data, _ := ioutil.ReadAll(r.Body)
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_json", "Error parsing JSON body.")
+				return
+			}
+		}
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "blocks" && parts[2] == "children" && r.Method == http.MethodGet:
+		s.listChildren(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "blocks" && parts[2] == "children" && r.Method == http.MethodPatch:
+		s.appendChildren(w, parts[1], body)
+	case len(parts) == 2 && parts[0] == "blocks" && r.Method == http.MethodGet:
+		s.retrieveBlock(w, parts[1])
+	case len(parts) == 2 && parts[0] == "blocks" && r.Method == http.MethodPatch:
+		s.updateBlock(w, parts[1], body)
+	case len(parts) == 2 && parts[0] == "blocks" && r.Method == http.MethodDelete:
+		s.deleteBlock(w, parts[1])
+	case len(parts) == 2 && parts[0] == "pages" && r.Method == http.MethodGet:
+		s.retrievePage(w, parts[1])
+	case len(parts) == 1 && parts[0] == "search" && r.Method == http.MethodPost:
+		s.search(w, body)
+	case len(parts) == 3 && parts[0] == "databases" && parts[2] == "query" && r.Method == http.MethodPost:
+		s.queryDatabase(w, parts[1], body)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request_url", "Invalid request URL.")
+	}
+}
+
+func (s *Server) takeFailure(method, path string) *Failure {
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != method) || (f.Path != "" && f.Path != path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// lookup finds an object by ID, accepting IDs with or without dashes.
+func (s *Server) lookup(id string) (*object, bool) {
+	if obj, ok := s.objects[id]; ok {
+		return obj, true
+	}
+	compact := strings.ReplaceAll(id, "-", "")
+	for key, obj := range s.objects {
+		if strings.ReplaceAll(key, "-", "") == compact {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) liveChildren(parent *object) []*object {
+	var children []*object
+	for _, id := range parent.children {
+		child := s.objects[id]
+		if archived, _ := child.data["archived"].(bool); !archived {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func (s *Server) listChildren(w http.ResponseWriter, r *http.Request, id string) {
+	parent, ok := s.lookup(id)
+	if !ok || isArchived(parent) {
+		writeNotFound(w, id)
+		return
+	}
+	var results []map[string]interface{}
+	for _, child := range s.liveChildren(parent) {
+		results = append(results, child.data)
+	}
+	s.writeList(w, r.URL.Query().Get("start_cursor"), r.URL.Query().Get("page_size"), results, "block")
+}
+
+// writeList paginates results the way Notion does: the cursor is the ID of
+// the first result on the next page.
+func (s *Server) writeList(w http.ResponseWriter, cursor, pageSizeParam string, results []map[string]interface{}, listType string) {
+	pageSize := MaxPageSize
+	if pageSizeParam != "" {
+		size, err := strconv.Atoi(pageSizeParam)
+		if err != nil || size < 1 || size > MaxPageSize {
+			writeError(w, http.StatusBadRequest, "validation_error", "page_size should be a number between 1 and 100.")
+			return
+		}
+		pageSize = size
+	}
+	start := 0
+	if cursor != "" {
+		start = -1
+		for i, result := range results {
+			if result["id"] == cursor {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			writeError(w, http.StatusBadRequest, "validation_error", "start_cursor provided is invalid: "+cursor)
+			return
+		}
+	}
+	end := start + pageSize
+	if end > len(results) {
+		end = len(results)
+	}
+	page := results[start:end]
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	var nextCursor interface{}
+	if end < len(results) {
+		nextCursor = results[end]["id"]
+	}
+	writeJSON(w, map[string]interface{}{
+		"object":      "list",
+		"results":     page,
+		"next_cursor": nextCursor,
+		"has_more":    nextCursor != nil,
+		"type":        listType,
+		listType:      map[string]interface{}{},
+	})
+}
+
+func (s *Server) appendChildren(w http.ResponseWriter, id string, body map[string]interface{}) {
+	parent, ok := s.lookup(id)
+	if !ok || isArchived(parent) {
+		writeNotFound(w, id)
+		return
+	}
+	rawChildren, _ := body["children"].([]interface{})
+	if len(rawChildren) == 0 || len(rawChildren) > MaxPageSize {
+		writeError(w, http.StatusBadRequest, "validation_error", "body.children should have between 1 and 100 items.")
+		return
+	}
+	var children []map[string]interface{}
+	for _, raw := range rawChildren {
+		child, ok := raw.(map[string]interface{})
+		if !ok {
+			writeError(w, http.StatusBadRequest, "validation_error", "body.children should contain block objects.")
+			return
+		}
+		children = append(children, child)
+	}
+
+	index := len(parent.children)
+	if after, ok := body["after"].(string); ok && after != "" {
+		index = -1
+		for i, childID := range parent.children {
+			if sameID(childID, after) {
+				index = i + 1
+				break
+			}
+		}
+		if index < 0 {
+			writeError(w, http.StatusBadRequest, "validation_error", "after block "+after+" is not a child of "+id+".")
+			return
+		}
+	}
+
+	created, err := s.insertChildren(parent, children, index)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"object":      "list",
+		"results":     created,
+		"next_cursor": nil,
+		"has_more":    false,
+		"type":        "block",
+		"block":       map[string]interface{}{},
+	})
+}
+
+// insertChildren creates blocks under parent at index, recursing into any
+// nested "children", and returns the created top-level blocks.
+func (s *Server) insertChildren(parent *object, blocks []map[string]interface{}, index int) ([]map[string]interface{}, error) {
+	var created []*object
+	for _, block := range blocks {
+		obj, err := s.newBlock(parent, block)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, obj)
+	}
+
+	ids := make([]string, 0, len(parent.children)+len(created))
+	ids = append(ids, parent.children[:index]...)
+	for _, obj := range created {
+		ids = append(ids, obj.id)
+	}
+	ids = append(ids, parent.children[index:]...)
+	parent.children = ids
+	s.refreshHasChildren(parent)
+	s.touch(parent)
+
+	var results []map[string]interface{}
+	for _, obj := range created {
+		results = append(results, obj.data)
+	}
+	return results, nil
+}
+
+func (s *Server) newBlock(parent *object, block map[string]interface{}) (*object, error) {
+	blockType, _ := block["type"].(string)
+	if blockType == "" {
+		for key := range block {
+			if key != "object" && key != "children" {
+				blockType = key
+			}
+		}
+	}
+	payload, ok := block[blockType].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("body.children[].%s should be defined", blockType)
+	}
+	payload = clone(payload)
+	nested, _ := payload["children"].([]interface{})
+	delete(payload, "children")
+	if rawNested, ok := block["children"].([]interface{}); ok {
+		nested = append(nested, rawNested...)
+	}
+	normalizePayload(payload)
+
+	id := newID()
+	now := s.timestamp()
+	parentRef := map[string]interface{}{"type": "block_id", "block_id": parent.id}
+	if parent.kind == "page" {
+		parentRef = map[string]interface{}{"type": "page_id", "page_id": parent.id}
+	}
+	obj := &object{id: id, kind: "block", parentID: parent.id, data: map[string]interface{}{
+		"object":           "block",
+		"id":               id,
+		"parent":           parentRef,
+		"created_time":     now,
+		"last_edited_time": now,
+		"created_by":       map[string]interface{}{"object": "user", "id": "notiontest"},
+		"last_edited_by":   map[string]interface{}{"object": "user", "id": "notiontest"},
+		"has_children":     false,
+		"archived":         false,
+		"type":             blockType,
+		blockType:          payload,
+	}}
+	s.objects[id] = obj
+
+	var children []map[string]interface{}
+	for _, raw := range nested {
+		child, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("children should contain block objects")
+		}
+		children = append(children, child)
+	}
+	if len(children) > 0 {
+		if _, err := s.insertChildren(obj, children, 0); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (s *Server) retrieveBlock(w http.ResponseWriter, id string) {
+	obj, ok := s.lookup(id)
+	if !ok || obj.kind != "block" {
+		writeNotFound(w, id)
+		return
+	}
+	writeJSON(w, obj.data)
+}
+
+// updateBlock merges the type payload in body into the block, replacing each
+// field given, and handles archiving through the "archived" field.
+func (s *Server) updateBlock(w http.ResponseWriter, id string, body map[string]interface{}) {
+	obj, ok := s.lookup(id)
+	if !ok || obj.kind != "block" {
+		writeNotFound(w, id)
+		return
+	}
+	if archived, ok := body["archived"].(bool); ok {
+		if archived {
+			s.archive(obj)
+		} else {
+			obj.data["archived"] = false
+			s.touch(obj)
+		}
+	}
+	blockType := obj.data["type"].(string)
+	for key, value := range body {
+		if key == "archived" || key == "type" {
+			continue
+		}
+		if key != blockType {
+			writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("body.%s is not a property of a %s block.", key, blockType))
+			return
+		}
+		if isArchived(obj) {
+			writeError(w, http.StatusBadRequest, "validation_error", "Can't edit block that is archived. You must unarchive the block before editing.")
+			return
+		}
+		update, ok := value.(map[string]interface{})
+		if !ok {
+			writeError(w, http.StatusBadRequest, "validation_error", "body."+key+" should be an object.")
+			return
+		}
+		payload := obj.data[blockType].(map[string]interface{})
+		for field, fieldValue := range clone(update) {
+			payload[field] = fieldValue
+		}
+		normalizePayload(payload)
+		s.touch(obj)
+	}
+	writeJSON(w, obj.data)
+}
+
+func (s *Server) deleteBlock(w http.ResponseWriter, id string) {
+	obj, ok := s.lookup(id)
+	if !ok || obj.kind != "block" || isArchived(obj) {
+		writeNotFound(w, id)
+		return
+	}
+	s.archive(obj)
+	writeJSON(w, obj.data)
+}
+
+func (s *Server) archive(obj *object) {
+	obj.data["archived"] = true
+	s.touch(obj)
+	if parent, ok := s.objects[obj.parentID]; ok {
+		s.refreshHasChildren(parent)
+	}
+}
+
+func (s *Server) retrievePage(w http.ResponseWriter, id string) {
+	obj, ok := s.lookup(id)
+	if !ok || obj.kind != "page" {
+		writeNotFound(w, id)
+		return
+	}
+	writeJSON(w, obj.data)
+}
+
+// search matches the query case-insensitively against page and database
+// titles and honours filter.value of "page" or "database".
+func (s *Server) search(w http.ResponseWriter, body map[string]interface{}) {
+	query, _ := body["query"].(string)
+	var kind string
+	if filter, ok := body["filter"].(map[string]interface{}); ok {
+		kind, _ = filter["value"].(string)
+	}
+	var results []map[string]interface{}
+	for _, id := range s.order {
+		obj := s.objects[id]
+		if isArchived(obj) || (kind != "" && obj.kind != kind) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(title(obj)), strings.ToLower(query)) {
+			results = append(results, obj.data)
+		}
+	}
+	cursor, _ := body["start_cursor"].(string)
+	s.writeList(w, cursor, pageSizeFromBody(body), results, "page_or_database")
+}
+
+// queryDatabase returns the pages of a database in creation order. Filters
+// and sorts are accepted but not applied.
+func (s *Server) queryDatabase(w http.ResponseWriter, id string, body map[string]interface{}) {
+	database, ok := s.lookup(id)
+	if !ok || database.kind != "database" {
+		writeNotFound(w, id)
+		return
+	}
+	var results []map[string]interface{}
+	for _, pageID := range s.order {
+		obj := s.objects[pageID]
+		if obj.kind == "page" && obj.parentID == database.id && !isArchived(obj) {
+			results = append(results, obj.data)
+		}
+	}
+	cursor, _ := body["start_cursor"].(string)
+	s.writeList(w, cursor, pageSizeFromBody(body), results, "page_or_database")
+}
+
+func (s *Server) touch(obj *object) {
+	obj.data["last_edited_time"] = s.timestamp()
+}
+
+func (s *Server) refreshHasChildren(obj *object) {
+	if obj.kind == "block" {
+		obj.data["has_children"] = len(s.liveChildren(obj)) > 0
+	}
+}
+
+func isArchived(obj *object) bool {
+	archived, _ := obj.data["archived"].(bool)
+	return archived
+}
+
+func sameID(a, b string) bool {
+	return strings.ReplaceAll(a, "-", "") == strings.ReplaceAll(b, "-", "")
+}
+
+func title(obj *object) string {
+	var parts []interface{}
+	if obj.kind == "database" {
+		parts, _ = obj.data["title"].([]interface{})
+	} else {
+		props, _ := obj.data["properties"].(map[string]interface{})
+		for _, value := range props {
+			if prop, _ := value.(map[string]interface{}); prop["type"] == "title" {
+				parts, _ = prop["title"].([]interface{})
+			}
+		}
+	}
+	var text strings.Builder
+	for _, part := range parts {
+		if segment, ok := part.(map[string]interface{}); ok {
+			plain, _ := segment["plain_text"].(string)
+			text.WriteString(plain)
+		}
+	}
+	return text.String()
+}
+
+func pageSizeFromBody(body map[string]interface{}) string {
+	if size, ok := body["page_size"].(float64); ok {
+		return strconv.Itoa(int(size))
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object":     "error",
+		"status":     status,
+		"code":       code,
+		"message":    message,
+		"request_id": newID(),
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, id string) {
+	writeError(w, http.StatusNotFound, "object_not_found",
+		"Could not find block with ID: "+id+". Make sure the relevant pages and databases are shared with your integration.")
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "validation_error"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "restricted_resource"
+	case http.StatusNotFound:
+		return "object_not_found"
+	case http.StatusConflict:
+		return "conflict_error"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return "service_unavailable"
+	case http.StatusGatewayTimeout:
+		return "gateway_timeout"
+	}
+	return "internal_server_error"
+}
+
+// clone deep-copies a JSON-shaped map by round-tripping it through JSON.
+func clone(value map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(value)
+	var copied map[string]interface{}
+	json.Unmarshal(data, &copied)
+	return copied
+}
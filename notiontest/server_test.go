@@ -0,0 +1,134 @@
+package notiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func call(t *testing.T, server *Server, method, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reqBody).Encode(body)
+	}
+	req, err := http.NewRequest(method, server.URL+path, &reqBody)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Notion-Version", "2022-06-28")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func texts(results interface{}) []string {
+	var texts []string
+	for _, raw := range results.([]interface{}) {
+		block := raw.(map[string]interface{})
+		payload := block[block["type"].(string)].(map[string]interface{})
+		segments := payload["rich_text"].([]interface{})
+		texts = append(texts, segments[0].(map[string]interface{})["plain_text"].(string))
+	}
+	return texts
+}
+
+func TestAppendAfterAndPaginate(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, ToDo("one", false), ToDo("three", false))
+
+	status, _ := call(t, server, "PATCH", "/blocks/"+pageID+"/children", map[string]interface{}{
+		"children": []interface{}{ToDo("two", false)},
+		"after":    ids[0],
+	})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200 appending after a child, got: %d", status)
+	}
+
+	status, page := call(t, server, "GET", "/blocks/"+pageID+"/children?page_size=2", nil)
+	if status != http.StatusOK || page["has_more"] != true {
+		t.Fatalf("Expected a first page with more to come, got: %d %v", status, page)
+	}
+	got := texts(page["results"])
+	_, rest := call(t, server, "GET", "/blocks/"+pageID+"/children?page_size=2&start_cursor="+page["next_cursor"].(string), nil)
+	got = append(got, texts(rest["results"])...)
+
+	if want := []string{"one", "two", "three"}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Expected %v, got: %v", want, got)
+	}
+}
+
+func TestArchivedBlocksAreHidden(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, ToDo("keep", false), ToDo("drop", false))
+
+	if status, _ := call(t, server, "DELETE", "/blocks/"+ids[1], nil); status != http.StatusOK {
+		t.Fatalf("Expected 200 deleting a block, got: %d", status)
+	}
+	if children := server.Children(pageID); len(children) != 1 {
+		t.Errorf("Expected 1 live child, got: %d", len(children))
+	}
+	if status, _ := call(t, server, "PATCH", "/blocks/"+ids[1], map[string]interface{}{"to_do": map[string]interface{}{"checked": true}}); status != http.StatusBadRequest {
+		t.Errorf("Expected editing an archived block to fail, got: %d", status)
+	}
+}
+
+func TestUpdateBlockMergesPayload(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, ToDo("task", false))
+
+	status, block := call(t, server, "PATCH", "/blocks/"+ids[0], map[string]interface{}{"to_do": map[string]interface{}{"checked": true}})
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d", status)
+	}
+	payload := block["to_do"].(map[string]interface{})
+	if payload["checked"] != true || len(payload["rich_text"].([]interface{})) != 1 {
+		t.Errorf("Expected checked to change and the text to stay, got: %v", payload)
+	}
+}
+
+func TestSearchAndDatabaseQuery(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddPage("Team tasks")
+	server.AddPage("Notes")
+	databaseID := server.AddDatabase("Projects")
+	server.AddDatabasePage(databaseID, "Launch", nil)
+	server.AddDatabasePage(databaseID, "Hiring", nil)
+
+	_, found := call(t, server, "POST", "/search", map[string]interface{}{"query": "tasks"})
+	if results := found["results"].([]interface{}); len(results) != 1 {
+		t.Errorf("Expected search to find 1 page, got: %d", len(results))
+	}
+	_, pages := call(t, server, "POST", "/databases/"+databaseID+"/query", map[string]interface{}{"page_size": 1})
+	if results := pages["results"].([]interface{}); len(results) != 1 || pages["has_more"] != true {
+		t.Errorf("Expected the first of two database pages, got: %v", pages)
+	}
+}
+
+func TestInjectedFailures(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.Fail(Failure{Method: "GET", Status: http.StatusTooManyRequests, RetryAfter: 1, Times: 1})
+
+	if status, body := call(t, server, "GET", "/blocks/"+pageID+"/children", nil); status != http.StatusTooManyRequests || body["code"] != "rate_limited" {
+		t.Errorf("Expected an injected 429, got: %d %v", status, body)
+	}
+	if status, _ := call(t, server, "GET", "/blocks/"+pageID+"/children", nil); status != http.StatusOK {
+		t.Errorf("Expected the failure to be used up, got: %d", status)
+	}
+}
@@ -0,0 +1,54 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is how many mutations ForEachTask keeps in flight. The
+// client's rate limiter still decides how fast they actually go out.
+const DefaultConcurrency = 3
+
+// TaskResult is the outcome of applying an operation to one task.
+type TaskResult struct {
+	Task *Task
+	Err  error
+}
+
+// ForEachTask applies op to every task with at most workers calls in flight
+// and returns one result per task, in the same order as tasks. The tasks are
+// expected to come from a single TaskList snapshot, so no call needs to fetch
+// the page again.
+func ForEachTask(ctx context.Context, tasks []*Task, workers int, op func(ctx context.Context, task *Task) error) []TaskResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]TaskResult, len(tasks))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				err := ctx.Err()
+				if err == nil {
+					err = op(ctx, tasks[i])
+				}
+				results[i] = TaskResult{Task: tasks[i], Err: err}
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEachTaskResolvesOnceAndMutatesMany(t *testing.T) {
+	var mu sync.Mutex
+	gets, patches := 0, 0
+	var children []Block
+	for i := 1; i <= 6; i++ {
+		children = append(children, todoBlock(fmt.Sprintf("block%d", i), fmt.Sprintf("task %d", i)))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			gets++
+			json.NewEncoder(w).Encode(BlockList{Results: children})
+			return
+		}
+		patches++
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRateLimit(0, 0))
+	ctx := context.Background()
+	list, err := client.GetTaskList(ctx, "pageID")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	tasks, err := list.Select(Selector{Positions: []int{2, 4, 5, 6}})
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	results := ForEachTask(ctx, tasks, DefaultConcurrency, func(ctx context.Context, task *Task) error {
+		_, err := client.SetToDoChecked(ctx, task.Block, true)
+		return err
+	})
+
+	if gets != 1 || patches != 4 {
+		t.Errorf("Expected 1 GET and 4 PATCHes, got %d and %d", gets, patches)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Errorf("Got error for task %d: %v", result.Task.Position, result.Err)
+		}
+		if result.Task != tasks[i] {
+			t.Errorf("Expected results in task order, result %d is task %d", i, result.Task.Position)
+		}
+	}
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	limiter := newRateLimiter(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Got error: %v", err)
+		}
+	}
+	// The first two requests are a burst; the other four wait 10ms each.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, took %v", elapsed)
+	}
+}
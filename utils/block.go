@@ -5,54 +5,96 @@
 package utils
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"strings"
 	"time"
 )
 
-var baseURL = "https://api.notion.com/v1"
-
-var blocks []Block
-
 type ToDo struct {
 	Checked  bool       `json:"checked"`
-	Color    string     `json:"color"`
+	Color    string     `json:"color,omitempty"`
 	RichText []RichText `json:"rich_text"`
 }
 
+// PlainText joins the text of every rich text segment in the to-do.
+func (t *ToDo) PlainText() string {
+	return PlainText(t.RichText)
+}
+
 type RichText struct {
-	Annotations Annotation  `json:"annotations"`
-	Href        interface{} `json:"href"`
-	PlainText   string      `json:"plain_text"`
-	Text        Text        `json:"text"`
-	Type        string      `json:"type"`
+	Type        string          `json:"type"`
+	Text        *Text           `json:"text,omitempty"`
+	Mention     json.RawMessage `json:"mention,omitempty"`
+	Equation    *Equation       `json:"equation,omitempty"`
+	Annotations Annotation      `json:"annotations"`
+	PlainText   string          `json:"plain_text,omitempty"`
+	Href        *string         `json:"href,omitempty"`
 }
 
 type Annotation struct {
 	Bold          bool   `json:"bold"`
 	Code          bool   `json:"code"`
-	Color         string `json:"color"`
+	Color         string `json:"color,omitempty"`
 	Italic        bool   `json:"italic"`
 	Strikethrough bool   `json:"strikethrough"`
 	Underline     bool   `json:"underline"`
 }
 
 type Text struct {
-	Content string      `json:"content"`
-	Link    interface{} `json:"link"`
+	Content string `json:"content"`
+	Link    *Link  `json:"link,omitempty"`
 }
 
+type Link struct {
+	URL string `json:"url"`
+}
+
+type Equation struct {
+	Expression string `json:"expression"`
+}
+
+// Block is a Notion block. Exactly one of the payload fields is set, the one
+// named by Type. Payloads of types this package does not model are kept
+// verbatim in Raw so that decoding and re-encoding a block loses nothing.
 type Block struct {
-	Object         string `json:"object"`
-	ID             string `json:"id"`
-	CreatedTime    string `json:"created_time"`
-	LastEditedTime string `json:"last_edited_time"`
-	Type           string `json:"type"`
-	HasChildren    bool   `json:"has_children"`
-	ToDo           *ToDo  `json:"to_do,omitempty"`
+	Object         string  `json:"object"`
+	ID             string  `json:"id"`
+	Parent         *Parent `json:"parent,omitempty"`
+	CreatedTime    string  `json:"created_time"`
+	LastEditedTime string  `json:"last_edited_time"`
+	Type           string  `json:"type"`
+	HasChildren    bool    `json:"has_children"`
+	Archived       bool    `json:"archived,omitempty"`
+
+	Paragraph        *TextBlock   `json:"paragraph,omitempty"`
+	Heading1         *Heading     `json:"heading_1,omitempty"`
+	Heading2         *Heading     `json:"heading_2,omitempty"`
+	Heading3         *Heading     `json:"heading_3,omitempty"`
+	BulletedListItem *TextBlock   `json:"bulleted_list_item,omitempty"`
+	NumberedListItem *TextBlock   `json:"numbered_list_item,omitempty"`
+	ToDo             *ToDo        `json:"to_do,omitempty"`
+	Toggle           *TextBlock   `json:"toggle,omitempty"`
+	Quote            *TextBlock   `json:"quote,omitempty"`
+	Callout          *Callout     `json:"callout,omitempty"`
+	Code             *Code        `json:"code,omitempty"`
+	Divider          *Divider     `json:"divider,omitempty"`
+	ChildPage        *ChildPage   `json:"child_page,omitempty"`
+	ChildDatabase    *ChildPage   `json:"child_database,omitempty"`
+	Bookmark         *Bookmark    `json:"bookmark,omitempty"`
+	Table            *Table       `json:"table,omitempty"`
+	TableRow         *TableRow    `json:"table_row,omitempty"`
+	ColumnList       *ColumnList  `json:"column_list,omitempty"`
+	Column           *Column      `json:"column,omitempty"`
+	SyncedBlock      *SyncedBlock `json:"synced_block,omitempty"`
+
+	Raw json.RawMessage `json:"-"`
+
+	// Children holds the block's children when they were fetched with
+	// GetBlockTree. It is not part of the block's JSON.
+	Children []Block `json:"-"`
 }
 
 type BlockList struct {
@@ -65,250 +107,226 @@ type BlockList struct {
 	DeveloperSurvey string   `json:"developer_survey"`
 }
 
-func GetBlocks(notionAPIKey, pageID string) ([]Block, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", baseURL+"/blocks/"+pageID+"/children", nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var blockList BlockList
-
-	err = json.NewDecoder(resp.Body).Decode(&blockList)
+func (c *NotionClient) GetBlocks(ctx context.Context, pageID string) ([]Block, error) {
+	list, err := c.GetTaskList(ctx, pageID)
 	if err != nil {
 		return nil, err
 	}
 
 	var blocks []Block
-	for _, result := range blockList.Results {
-		if result.Object == "block" && result.ToDo != nil && len(result.ToDo.RichText) > 0 {
-			blocks = append(blocks, result)
-		}
+	for _, task := range list.Tasks {
+		blocks = append(blocks, task.Block)
 	}
 	return blocks, nil
 }
 
-func GetToDoBlocks(notionAPIKey, blockID string, localTimezone *time.Location) ([]string, error) {
-	blocks, err := GetBlocks(notionAPIKey, blockID)
+func (c *NotionClient) GetToDoBlocks(ctx context.Context, blockID string, localTimezone *time.Location) ([]string, error) {
+	list, err := c.GetTaskList(ctx, blockID)
 	if err != nil {
 		return nil, err
 	}
 	var todoBlocks []string
-	for _, block := range blocks {
-		if block.ToDo != nil {
-			var checked string
-			if block.ToDo.Checked {
-				checked = "X"
-			} else {
-				checked = " "
-			}
-			lastEditedTime, err := time.Parse(time.RFC3339, block.LastEditedTime)
-			if err != nil {
-				return nil, err
-			}
-			truncatedTime := lastEditedTime.In(localTimezone).Truncate(time.Minute)
-
-			element := fmt.Sprintf("%d [%s] %s (%s)", len(todoBlocks)+1, checked, block.ToDo.RichText[0].PlainText, truncatedTime.Format("2006-01-02 15:04"))
-			todoBlocks = append(todoBlocks, element)
+	for _, task := range list.Tasks {
+		block := task.Block
+		var checked string
+		if block.ToDo.Checked {
+			checked = "X"
+		} else {
+			checked = " "
+		}
+		lastEditedTime, err := time.Parse(time.RFC3339, block.LastEditedTime)
+		if err != nil {
+			return nil, err
 		}
+		truncatedTime := lastEditedTime.In(localTimezone).Truncate(time.Minute)
+
+		indent := strings.Repeat("  ", task.Depth())
+		element := fmt.Sprintf("%s%s [%s] %s (%s)", indent, task.Number, checked, RenderRichText(block.ToDo.RichText), truncatedTime.Format("2006-01-02 15:04"))
+		todoBlocks = append(todoBlocks, element)
 	}
 
 	return todoBlocks, nil
 }
 
-func AddNewToDoItem(notionAPIKey, pageID, text string) error {
-	client := &http.Client{}
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"children": []map[string]interface{}{
-			{
-				"object": "block",
-				"type":   "to_do",
-				"to_do": map[string]interface{}{
-					"rich_text": []map[string]interface{}{
-						{
-							"type": "text",
-							"text": map[string]interface{}{
-								"content": text,
-							},
-						},
-					},
-				},
-			},
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("error marshalling request body: %v", err)
-	}
-
-	req, err := http.NewRequest("PATCH", baseURL+"/blocks/"+pageID+"/children", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
-	}
+// AddNewToDoItem appends a to-do with text taken literally.
+func (c *NotionClient) AddNewToDoItem(ctx context.Context, pageID, text string) error {
+	return c.do(ctx, "PATCH", "/blocks/"+pageID+"/children", newToDoRequest(PlainRichText(text), ""), nil)
+}
 
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
+// AddToDo appends an unchecked to-do with the given rich text to the end of
+// parentID's children and returns the block Notion created.
+func (c *NotionClient) AddToDo(ctx context.Context, parentID string, text []RichText) (*Block, error) {
+	return c.AddToDoAfter(ctx, parentID, "", text)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// AddToDoAfter is AddToDo, but inserts the to-do right after the child
+// afterID instead of at the end when afterID is not empty.
+func (c *NotionClient) AddToDoAfter(ctx context.Context, parentID, afterID string, text []RichText) (*Block, error) {
+	var created BlockList
+	if err := c.do(ctx, "PATCH", "/blocks/"+parentID+"/children", newToDoRequest(text, afterID), &created); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, message: %s", resp.StatusCode, string(bodyBytes))
+	if len(created.Results) == 0 {
+		return nil, fmt.Errorf("notion did not return the new block")
 	}
-
-	return nil
+	return &created.Results[len(created.Results)-1], nil
 }
 
-func GetBlockID(notionAPIKey, pageID string, order int) (string, error) {
-	if order < 1 {
-		return "", fmt.Errorf("order must be greater than 0")
+// ErrFirstChild is returned by AddToDoBefore when asked to insert above the
+// first child of a block or page without recreating it.
+var ErrFirstChild = errors.New("notion can only insert blocks after another block, not above the first one")
+
+// AddToDoBefore inserts a to-do right before the block before. Notion can
+// only insert after a block, so when before is the first child of its parent
+// it returns ErrFirstChild, unless recreate is set. Then the to-do is
+// inserted after before, and before is recreated, with its children, behind
+// the new to-do: it gets a new ID and loses its created time and comments.
+// If recreating it fails, the new to-do is removed again, and a
+// RollbackError names anything that could not be removed.
+func (c *NotionClient) AddToDoBefore(ctx context.Context, before Block, text []RichText, recreate bool) (*Block, error) {
+	parentID := before.ParentID()
+	if parentID == "" {
+		return nil, fmt.Errorf("block %s has no parent", before.ID)
 	}
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", baseURL+"/blocks/"+pageID+"/children", nil)
+	siblings, err := c.GetAllBlockChildren(ctx, parentID)
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
-
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	index := -1
+	for i, sibling := range siblings {
+		if sibling.ID == before.ID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("block %s is no longer on the page", before.ID)
+	}
+	if index > 0 {
+		return c.AddToDoAfter(ctx, parentID, siblings[index-1].ID, text)
+	}
+	if !recreate {
+		return nil, ErrFirstChild
 	}
-	defer resp.Body.Close()
 
-	var blockList BlockList
-	err = json.NewDecoder(resp.Body).Decode(&blockList)
+	created, err := c.AddToDoAfter(ctx, parentID, before.ID, text)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	if order > len(blockList.Results) {
-		return "", fmt.Errorf("order number exceeds the number of blocks")
+	if _, err := c.MoveBlock(ctx, before, parentID, created.ID); err != nil {
+		return nil, c.rollback(err, created.ID)
 	}
-
-	return blockList.Results[order-1].ID, nil
-
+	return created, nil
 }
 
-func MarkToDoBlockChecked(notionAPIKey, pageID string, order int) error {
-
-	blockID, err := GetBlockID(notionAPIKey, pageID, order)
-	if err != nil {
-		return err
-	}
-	client := &http.Client{}
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"to_do": map[string]interface{}{
-			"checked": true,
+func newToDoRequest(text []RichText, afterID string) map[string]interface{} {
+	reqBody := map[string]interface{}{
+		"children": []map[string]interface{}{
+			{
+				"object": "block",
+				"type":   TypeToDo,
+				TypeToDo: ToDo{RichText: requestRichText(text)},
+			},
 		},
-	})
-	if err != nil {
-		return fmt.Errorf("error marshalling request body: %v", err)
 	}
-
-	req, err := http.NewRequest("PATCH", baseURL+"/blocks/"+blockID, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
+	if afterID != "" {
+		reqBody["after"] = afterID
 	}
+	return reqBody
+}
 
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
-
-	resp, err := client.Do(req)
-
+// GetBlockID returns the ID of the task shown at position order in `list`.
+func (c *NotionClient) GetBlockID(ctx context.Context, pageID string, order int) (string, error) {
+	task, err := c.resolveTask(ctx, pageID, order)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer resp.Body.Close()
+	return task.Block.ID, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+func (c *NotionClient) resolveTask(ctx context.Context, pageID string, order int) (*Task, error) {
+	list, err := c.GetTaskList(ctx, pageID)
+	if err != nil {
+		return nil, err
 	}
+	return list.Resolve(order)
+}
 
-	return nil
+// MarkToDoBlockChecked checks the task shown at position order. It fetches the
+// page to resolve the position, so to change several tasks resolve them once
+// with GetTaskList and apply SetToDoChecked through ForEachTask instead.
+func (c *NotionClient) MarkToDoBlockChecked(ctx context.Context, pageID string, order int) error {
+	return c.setToDoChecked(ctx, pageID, order, true)
 }
 
-func MarkToDoBlockUnChecked(notionAPIKey, pageID string, order int) error {
+func (c *NotionClient) MarkToDoBlockUnChecked(ctx context.Context, pageID string, order int) error {
+	return c.setToDoChecked(ctx, pageID, order, false)
+}
 
-	blockID, err := GetBlockID(notionAPIKey, pageID, order)
+func (c *NotionClient) setToDoChecked(ctx context.Context, pageID string, order int, checked bool) error {
+	task, err := c.resolveTask(ctx, pageID, order)
 	if err != nil {
 		return err
 	}
-	client := &http.Client{}
-	reqBody, err := json.Marshal(map[string]interface{}{
+	_, err = c.SetToDoChecked(ctx, task.Block, checked)
+	return err
+}
+
+// SetToDoChecked checks or unchecks a single to-do block and returns the
+// block as Notion stored it.
+func (c *NotionClient) SetToDoChecked(ctx context.Context, block Block, checked bool) (*Block, error) {
+	if err := requireToDo(block); err != nil {
+		return nil, err
+	}
+	reqBody := map[string]interface{}{
 		"to_do": map[string]interface{}{
-			"checked": false,
+			"checked": checked,
 		},
-	})
-	if err != nil {
-		return fmt.Errorf("error marshalling request body: %v", err)
 	}
 
-	req, err := http.NewRequest("PATCH", baseURL+"/blocks/"+blockID, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
+	var updated Block
+	if err := c.do(ctx, "PATCH", "/blocks/"+block.ID, reqBody, &updated); err != nil {
+		return nil, err
 	}
+	return &updated, nil
+}
 
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
-
-	resp, err := client.Do(req)
-
-	if err != nil {
-		return err
+// SetToDoText replaces the text of a to-do block, keeping its checked state,
+// colour and children, and returns the block as Notion stored it.
+func (c *NotionClient) SetToDoText(ctx context.Context, block Block, text []RichText) (*Block, error) {
+	if err := requireToDo(block); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	reqBody := map[string]interface{}{
+		"to_do": ToDo{
+			RichText: requestRichText(text),
+			Checked:  block.ToDo.Checked,
+			Color:    block.ToDo.Color,
+		},
 	}
 
-	return nil
+	var updated Block
+	if err := c.do(ctx, "PATCH", "/blocks/"+block.ID, reqBody, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
 }
 
-func DeleteToDoBlock(notionAPIKey, pageID string, order int) error {
-	blockID, err := GetBlockID(notionAPIKey, pageID, order)
+// DeleteToDoBlock removes the task shown at position order. Like
+// MarkToDoBlockChecked it fetches the page for every call.
+func (c *NotionClient) DeleteToDoBlock(ctx context.Context, pageID string, order int) error {
+	task, err := c.resolveTask(ctx, pageID, order)
 	if err != nil {
 		return err
 	}
-	client := &http.Client{}
-	req, err := http.NewRequest("DELETE", baseURL+"/blocks/"+blockID, nil)
-	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Add("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
+	return c.DeleteToDo(ctx, task.Block)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
+// DeleteToDo archives a single to-do block.
+func (c *NotionClient) DeleteToDo(ctx context.Context, block Block) error {
+	if err := requireToDo(block); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
 
-	return nil
+	return c.do(ctx, "DELETE", "/blocks/"+block.ID, nil, nil)
 }
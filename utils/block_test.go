@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -10,13 +11,10 @@ import (
 // Mock server
 var (
 	mockServer    *httptest.Server
+	mockClient    *NotionClient
 	deletedBlocks map[string]bool
 )
 
-func SetBaseURL(url string) {
-	baseURL = url
-}
-
 func mockBlock(texts []string) Block {
 
 	richTexts := make([]RichText, len(texts))
@@ -83,7 +81,7 @@ func setup() {
 			}
 		}
 	}))
-	SetBaseURL(mockServer.URL)
+	mockClient = NewNotionClient("fakeKey", WithBaseURL(mockServer.URL))
 }
 
 func teardown() {
@@ -94,9 +92,8 @@ func TestGetBlocks(t *testing.T) {
 	setup()
 	defer teardown()
 
-	notionAPIKey := "fakeKey"
 	pageID := "pageID"
-	blocks, err := GetBlocks(notionAPIKey, pageID)
+	blocks, err := mockClient.GetBlocks(context.Background(), pageID)
 
 	if err != nil {
 		t.Errorf("Got error: %v", err)
@@ -110,9 +107,8 @@ func TestGetBlocks(t *testing.T) {
 func TestGetBlocksIfRichTextIsEmpty(t *testing.T) {
 	setup()
 	defer teardown()
-	notionAPIKey := "fakeKey"
 	pageID := "pageWithToDoWithNoContent"
-	blocks, err := GetBlocks(notionAPIKey, pageID)
+	blocks, err := mockClient.GetBlocks(context.Background(), pageID)
 
 	if err != nil {
 		t.Errorf("Got error: %v", err)
@@ -128,10 +124,9 @@ func TestAddNewToDoItem(t *testing.T) {
 	setup()
 	defer teardown()
 
-	notionAPIKey := "fakeKey"
 	pageID := "pageID"
 	toDoText := "new todo"
-	err := AddNewToDoItem(notionAPIKey, pageID, toDoText)
+	err := mockClient.AddNewToDoItem(context.Background(), pageID, toDoText)
 
 	if err != nil {
 		t.Errorf("Got error: %v", err)
@@ -142,11 +137,10 @@ func TestDeleteToDoBlock(t *testing.T) {
 	setup()
 	defer teardown()
 
-	notionAPIKey := "fakeKey"
 	pageID := "pageID"
 	order := 1
 
-	err := DeleteToDoBlock(notionAPIKey, pageID, order)
+	err := mockClient.DeleteToDoBlock(context.Background(), pageID, order)
 
 	if err != nil {
 		t.Errorf("Error deleting to-do block with ID %s and order %d: %v", pageID, order, err)
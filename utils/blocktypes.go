@@ -0,0 +1,270 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Block types this package models with a typed payload.
+const (
+	TypeParagraph        = "paragraph"
+	TypeHeading1         = "heading_1"
+	TypeHeading2         = "heading_2"
+	TypeHeading3         = "heading_3"
+	TypeBulletedListItem = "bulleted_list_item"
+	TypeNumberedListItem = "numbered_list_item"
+	TypeToDo             = "to_do"
+	TypeToggle           = "toggle"
+	TypeQuote            = "quote"
+	TypeCallout          = "callout"
+	TypeCode             = "code"
+	TypeDivider          = "divider"
+	TypeChildPage        = "child_page"
+	TypeChildDatabase    = "child_database"
+	TypeBookmark         = "bookmark"
+	TypeTable            = "table"
+	TypeTableRow         = "table_row"
+	TypeColumnList       = "column_list"
+	TypeColumn           = "column"
+	TypeSyncedBlock      = "synced_block"
+)
+
+var knownBlockTypes = map[string]bool{
+	TypeParagraph:        true,
+	TypeHeading1:         true,
+	TypeHeading2:         true,
+	TypeHeading3:         true,
+	TypeBulletedListItem: true,
+	TypeNumberedListItem: true,
+	TypeToDo:             true,
+	TypeToggle:           true,
+	TypeQuote:            true,
+	TypeCallout:          true,
+	TypeCode:             true,
+	TypeDivider:          true,
+	TypeChildPage:        true,
+	TypeChildDatabase:    true,
+	TypeBookmark:         true,
+	TypeTable:            true,
+	TypeTableRow:         true,
+	TypeColumnList:       true,
+	TypeColumn:           true,
+	TypeSyncedBlock:      true,
+}
+
+// Parent identifies the page, block, database or workspace an object
+// belongs to.
+type Parent struct {
+	Type       string `json:"type"`
+	PageID     string `json:"page_id,omitempty"`
+	BlockID    string `json:"block_id,omitempty"`
+	DatabaseID string `json:"database_id,omitempty"`
+	Workspace  bool   `json:"workspace,omitempty"`
+}
+
+// TextBlock is the payload shared by paragraphs, list items, toggles and
+// quotes.
+type TextBlock struct {
+	RichText []RichText `json:"rich_text"`
+	Color    string     `json:"color,omitempty"`
+}
+
+type Heading struct {
+	RichText     []RichText `json:"rich_text"`
+	Color        string     `json:"color,omitempty"`
+	IsToggleable bool       `json:"is_toggleable,omitempty"`
+}
+
+type Callout struct {
+	RichText []RichText `json:"rich_text"`
+	// Icon is an emoji, external or file object; it is kept as is.
+	Icon  json.RawMessage `json:"icon,omitempty"`
+	Color string          `json:"color,omitempty"`
+}
+
+type Code struct {
+	RichText []RichText `json:"rich_text"`
+	Caption  []RichText `json:"caption,omitempty"`
+	Language string     `json:"language"`
+}
+
+type Divider struct{}
+
+// ChildPage is the payload of child_page and child_database blocks.
+type ChildPage struct {
+	Title string `json:"title"`
+}
+
+type Bookmark struct {
+	URL     string     `json:"url"`
+	Caption []RichText `json:"caption,omitempty"`
+}
+
+type Table struct {
+	TableWidth      int  `json:"table_width"`
+	HasColumnHeader bool `json:"has_column_header"`
+	HasRowHeader    bool `json:"has_row_header"`
+}
+
+type TableRow struct {
+	Cells [][]RichText `json:"cells"`
+}
+
+type ColumnList struct{}
+
+type Column struct {
+	WidthRatio float64 `json:"width_ratio,omitempty"`
+}
+
+// SyncedBlock is the payload of a synced block. SyncedFrom is nil for the
+// original and points at the original for every duplicate.
+type SyncedBlock struct {
+	SyncedFrom *SyncedFrom `json:"synced_from"`
+}
+
+type SyncedFrom struct {
+	Type    string `json:"type"`
+	BlockID string `json:"block_id"`
+}
+
+// Known reports whether the block's type has a typed payload. Blocks of
+// other types carry their payload in Raw.
+func (b *Block) Known() bool {
+	return knownBlockTypes[b.Type]
+}
+
+// RichText returns the main text of the block, or nil for blocks such as
+// dividers, tables and child pages that have none.
+func (b *Block) RichText() []RichText {
+	switch {
+	case b.Paragraph != nil:
+		return b.Paragraph.RichText
+	case b.Heading1 != nil:
+		return b.Heading1.RichText
+	case b.Heading2 != nil:
+		return b.Heading2.RichText
+	case b.Heading3 != nil:
+		return b.Heading3.RichText
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem.RichText
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem.RichText
+	case b.ToDo != nil:
+		return b.ToDo.RichText
+	case b.Toggle != nil:
+		return b.Toggle.RichText
+	case b.Quote != nil:
+		return b.Quote.RichText
+	case b.Callout != nil:
+		return b.Callout.RichText
+	case b.Code != nil:
+		return b.Code.RichText
+	}
+	return nil
+}
+
+// PlainText returns the block's text without formatting. Child pages and
+// databases give their title and bookmarks their URL.
+func (b *Block) PlainText() string {
+	switch {
+	case b.ChildPage != nil:
+		return b.ChildPage.Title
+	case b.ChildDatabase != nil:
+		return b.ChildDatabase.Title
+	case b.Bookmark != nil:
+		return b.Bookmark.URL
+	}
+	return PlainText(b.RichText())
+}
+
+// Heading returns the payload of a heading of any level, or nil.
+func (b *Block) Heading() *Heading {
+	switch {
+	case b.Heading1 != nil:
+		return b.Heading1
+	case b.Heading2 != nil:
+		return b.Heading2
+	}
+	return b.Heading3
+}
+
+// IsContainer reports whether the block only groups other blocks, as
+// toggles, toggleable headings, columns and synced blocks do, so that to-dos
+// inside it belong to the page around it.
+func (b *Block) IsContainer() bool {
+	switch b.Type {
+	case TypeToggle, TypeColumnList, TypeColumn, TypeSyncedBlock:
+		return true
+	}
+	heading := b.Heading()
+	return heading != nil && heading.IsToggleable
+}
+
+// ChildrenID is the block whose children hold this block's content. It is
+// the block itself except for duplicate synced blocks, whose content lives
+// under the original.
+func (b *Block) ChildrenID() string {
+	if b.SyncedBlock != nil && b.SyncedBlock.SyncedFrom != nil && b.SyncedBlock.SyncedFrom.BlockID != "" {
+		return b.SyncedBlock.SyncedFrom.BlockID
+	}
+	return b.ID
+}
+
+// HeadingLevel returns 1, 2 or 3 for headings and 0 for any other block.
+func (b *Block) HeadingLevel() int {
+	switch b.Type {
+	case TypeHeading1:
+		return 1
+	case TypeHeading2:
+		return 2
+	case TypeHeading3:
+		return 3
+	}
+	return 0
+}
+
+// PlainText joins the plain text of rich text segments.
+func PlainText(segments []RichText) string {
+	var text strings.Builder
+	for _, segment := range segments {
+		text.WriteString(segment.PlainText)
+	}
+	return text.String()
+}
+
+// blockFields is Block without its methods, so that the JSON methods below
+// can use the default encoding for everything but Raw.
+type blockFields Block
+
+func (b *Block) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*blockFields)(b)); err != nil {
+		return err
+	}
+	b.Raw = nil
+	if b.Type == "" || b.Known() {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	b.Raw = fields[b.Type]
+	return nil
+}
+
+func (b Block) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(blockFields(b))
+	if err != nil || b.Raw == nil || b.Known() {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields[b.Type] = b.Raw
+	return json.Marshal(fields)
+}
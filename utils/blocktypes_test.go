@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const mixedBlocks = `[
+	{"object": "block", "id": "b1", "type": "heading_2", "heading_2": {"rich_text": [{"type": "text", "text": {"content": "Today"}, "plain_text": "Today"}], "is_toggleable": true}},
+	{"object": "block", "id": "b2", "type": "paragraph", "paragraph": {"rich_text": [{"type": "text", "text": {"content": "See "}, "plain_text": "See "}, {"type": "text", "text": {"content": "docs", "link": {"url": "https://example.com"}}, "plain_text": "docs", "href": "https://example.com"}]}},
+	{"object": "block", "id": "b3", "type": "code", "code": {"rich_text": [{"type": "text", "text": {"content": "go test"}, "plain_text": "go test"}], "language": "shell"}},
+	{"object": "block", "id": "b4", "type": "divider", "divider": {}},
+	{"object": "block", "id": "b5", "type": "child_page", "child_page": {"title": "Archive"}},
+	{"object": "block", "id": "b6", "type": "table", "table": {"table_width": 3, "has_column_header": true, "has_row_header": false}},
+	{"object": "block", "id": "b7", "type": "equation", "equation": {"expression": "e=mc^2"}}
+]`
+
+func TestDecodeBlockTypes(t *testing.T) {
+	var blocks []Block
+	if err := json.Unmarshal([]byte(mixedBlocks), &blocks); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if h := blocks[0]; h.HeadingLevel() != 2 || !h.Heading2.IsToggleable || h.PlainText() != "Today" {
+		t.Errorf("Expected a toggleable level 2 heading, got: %+v", h.Heading2)
+	}
+	if p := blocks[1]; p.PlainText() != "See docs" || p.RichText()[1].Text.Link.URL != "https://example.com" {
+		t.Errorf("Expected a paragraph with a link, got: %+v", p.Paragraph)
+	}
+	if c := blocks[2]; c.Code.Language != "shell" || c.PlainText() != "go test" {
+		t.Errorf("Expected a shell code block, got: %+v", c.Code)
+	}
+	if d := blocks[3]; d.Divider == nil || d.RichText() != nil {
+		t.Errorf("Expected a divider without text, got: %+v", d)
+	}
+	if p := blocks[4]; p.PlainText() != "Archive" {
+		t.Errorf("Expected the child page title, got: %q", p.PlainText())
+	}
+	if tb := blocks[5]; tb.Table.TableWidth != 3 || !tb.Table.HasColumnHeader {
+		t.Errorf("Expected a 3 column table with a header, got: %+v", tb.Table)
+	}
+	if e := blocks[6]; e.Known() || string(e.Raw) != `{"expression": "e=mc^2"}` {
+		t.Errorf("Expected the unknown payload to be kept, got: %s", e.Raw)
+	}
+}
+
+func TestUnknownBlockRoundTrips(t *testing.T) {
+	input := `{"object":"block","id":"b7","type":"equation","has_children":false,"equation":{"expression":"e=mc^2"}}`
+	var block Block
+	if err := json.Unmarshal([]byte(input), &block); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	output, err := json.Marshal(block)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	json.Unmarshal(output, &fields)
+	if string(fields["equation"]) != `{"expression":"e=mc^2"}` || string(fields["type"]) != `"equation"` {
+		t.Errorf("Expected the equation payload to survive, got: %s", output)
+	}
+}
@@ -0,0 +1,197 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// redacted replaces secrets in recorded requests.
+const redacted = "[REDACTED]"
+
+// RecordedRequest is the part of a request that a cassette keeps.
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the part of a response that a cassette keeps.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// Interaction is one request and the response Notion sent for it.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette is a file of recorded interactions, written by Recorder and
+// served back by Replayer.
+type Cassette struct {
+	RecordedAt   time.Time     `json:"recorded_at"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that sends requests on through Next and
+// writes every exchange to a cassette file, with the Authorization header
+// redacted. The file is rewritten after each request, so it is complete even
+// if the process exits abruptly.
+type Recorder struct {
+	Path string
+	Next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records to path. A nil next uses http.DefaultTransport.
+func NewRecorder(path string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Path: path, Next: next, cassette: Cassette{RecordedAt: time.Now().UTC()}}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := req.Header.Clone()
+	if headers.Get("Authorization") != "" {
+		headers.Set("Authorization", "Bearer "+redacted)
+	}
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.RequestURI(),
+			Headers: headers,
+			Body:    string(reqBody),
+		},
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: resp.Header.Clone(),
+			Body:    string(respBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.save(); err != nil {
+		return nil, fmt.Errorf("error writing cassette %s: %v", r.Path, err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, data, 0o600)
+}
+
+// readBody drains a request or response body and replaces it with a fresh
+// reader over the same bytes.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette
+// without touching the network. Each recorded interaction is used once, in
+// order, matched on method, path, query and JSON body; the host is ignored,
+// so a cassette recorded against api.notion.com replays anywhere.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// LoadReplayer reads a cassette written by Recorder.
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("error reading cassette %s: %v", path, err)
+	}
+	return &Replayer{interactions: cassette.Interactions, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method || recorded.URL != req.URL.RequestURI() || !sameJSON(recorded.Body, string(reqBody)) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Headers.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(interaction.Response.Body))),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+}
+
+// sameJSON compares two request bodies, ignoring key order and whitespace
+// when both are JSON.
+func sameJSON(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var left, right interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notioncli/notiontest"
+)
+
+func TestRecordThenReplayOffline(t *testing.T) {
+	server := notiontest.NewServer()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID, notiontest.ToDo("first task", false))
+
+	path := filepath.Join(t.TempDir(), "trace.json")
+	recorder := NewRecorder(path, nil)
+	client := NewNotionClient("super-secret-key", WithBaseURL(server.URL), WithTransport(recorder), WithRateLimit(0, 0))
+	ctx := context.Background()
+	if err := client.AddNewToDoItem(ctx, pageID, "second task"); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	recorded, err := client.GetBlocks(ctx, pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading cassette: %v", err)
+	}
+	if strings.Contains(string(data), "super-secret-key") {
+		t.Errorf("Expected the API key to be redacted from the cassette")
+	}
+
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	offline := NewNotionClient("another-key", WithBaseURL(server.URL), WithTransport(replayer), WithRetryPolicy(RetryPolicy{}))
+	if err := offline.AddNewToDoItem(ctx, pageID, "second task"); err != nil {
+		t.Fatalf("Got error replaying: %v", err)
+	}
+	replayed, err := offline.GetBlocks(ctx, pageID)
+	if err != nil {
+		t.Fatalf("Got error replaying: %v", err)
+	}
+	if len(replayed) != len(recorded) || replayed[1].ID != recorded[1].ID {
+		t.Errorf("Expected the replay to match the recording, got %d blocks instead of %d", len(replayed), len(recorded))
+	}
+
+	if _, err := offline.GetBlocks(ctx, pageID); err == nil {
+		t.Errorf("Expected an error once the recorded interactions are used up")
+	}
+}
@@ -0,0 +1,196 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL    = "https://api.notion.com/v1"
+	DefaultAPIVersion = "2022-06-28"
+	DefaultTimeout    = 30 * time.Second
+	DefaultUserAgent  = "notioncli"
+)
+
+// NotionClient sends authenticated requests to the Notion API. Every block and
+// page operation in this package is a method on it, so embedding tools only
+// need to construct one client and point it wherever they like.
+type NotionClient struct {
+	apiKey     string
+	baseURL    string
+	apiVersion string
+	userAgent  string
+	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter
+}
+
+// ClientOption configures a NotionClient in NewNotionClient.
+type ClientOption func(*NotionClient)
+
+// WithBaseURL points the client at a different API root, e.g. a test server.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *NotionClient) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithAPIVersion overrides the Notion-Version header sent with each request.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *NotionClient) {
+		c.apiVersion = version
+	}
+}
+
+// WithTimeout bounds each HTTP request. Zero disables the timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *NotionClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *NotionClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTransport replaces the underlying http.RoundTripper.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *NotionClient) {
+		c.httpClient.Transport = transport
+	}
+}
+
+func NewNotionClient(notionAPIKey string, opts ...ClientOption) *NotionClient {
+	c := &NotionClient{
+		apiKey:     notionAPIKey,
+		baseURL:    DefaultBaseURL,
+		apiVersion: DefaultAPIVersion,
+		userAgent:  DefaultUserAgent,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		retry:      DefaultRetryPolicy,
+		limiter:    newRateLimiter(DefaultRateLimit, DefaultBurst),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *NotionClient) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reqBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling request body: %v", err)
+		}
+		reader = bytes.NewReader(reqBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	req.Header.Set("Notion-Version", c.apiVersion)
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes a successful JSON response into out, which
+// may be nil when the caller does not need the response body. Rate limited and
+// transient failures are retried according to the client's RetryPolicy until
+// ctx is done, and every attempt waits its turn with the client's rate limiter.
+// A DELETE resent after such a failure succeeds if the block turns out to be
+// gone already, as the failed attempt may have archived it.
+func (c *NotionClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	idempotent := isIdempotent(method, path)
+	// A request retried after a server or network failure may have been
+	// applied by the attempt that failed.
+	mayBeApplied := false
+	for attempt := 0; ; attempt++ {
+		canRetry := attempt < c.retry.MaxRetries
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		req, err := c.newRequest(ctx, method, path, body)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if canRetry && shouldRetryError(err, idempotent) {
+				if err := sleepContext(ctx, c.retry.backoff(attempt)); err != nil {
+					return err
+				}
+				mayBeApplied = mayBeApplied || !isDialError(err)
+				continue
+			}
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			return json.NewDecoder(resp.Body).Decode(out)
+		}
+
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if canRetry && shouldRetryStatus(resp.StatusCode, idempotent) {
+			delay, ok := retryAfter(resp)
+			if !ok {
+				delay = c.retry.backoff(attempt)
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
+			mayBeApplied = mayBeApplied || resp.StatusCode != http.StatusTooManyRequests
+			continue
+		}
+		apiErr := newAPIError(resp.StatusCode, bodyBytes)
+		if method == http.MethodDelete && mayBeApplied && alreadyArchived(apiErr) {
+			return nil
+		}
+		return apiErr
+	}
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotionClientSendsConfiguredHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"object":"list","results":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewNotionClient("secret",
+		WithBaseURL(server.URL+"/"),
+		WithAPIVersion("2099-01-01"),
+		WithUserAgent("notioncli-test"),
+	)
+	if _, err := client.GetBlocks(context.Background(), "pageID"); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if got.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Expected bearer token, got: %q", got.Get("Authorization"))
+	}
+	if got.Get("Notion-Version") != "2099-01-01" {
+		t.Errorf("Expected Notion-Version 2099-01-01, got: %q", got.Get("Notion-Version"))
+	}
+	if got.Get("User-Agent") != "notioncli-test" {
+		t.Errorf("Expected User-Agent notioncli-test, got: %q", got.Get("User-Agent"))
+	}
+}
+
+func TestNotionClientReportsUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"Could not find block","request_id":"req-1"}`))
+	}))
+	defer server.Close()
+
+	client := NewNotionClient("secret", WithBaseURL(server.URL))
+	_, err := client.GetBlocks(context.Background(), "pageID")
+	if err == nil {
+		t.Fatalf("Expected an error for a 404 response")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got: %T", err)
+	}
+	if apiErr.Status != 404 || apiErr.Code != CodeObjectNotFound || apiErr.RequestID != "req-1" {
+		t.Errorf("Unexpected error fields: %+v", apiErr)
+	}
+}
+
+func TestNotionClientInfersCodeForNonJSONErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("<html>nope</html>"))
+	}))
+	defer server.Close()
+
+	client := NewNotionClient("secret", WithBaseURL(server.URL))
+	_, err := client.GetBlocks(context.Background(), "pageID")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeUnauthorized {
+		t.Errorf("Expected an unauthorized APIError, got: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// cleanupTimeout bounds the requests that undo a half-finished change. They
+// run even when the command's own context was cancelled.
+const cleanupTimeout = 30 * time.Second
+
+// notCreatable lists block types the API returns but cannot create.
+var notCreatable = map[string]bool{
+	TypeChildPage:     true,
+	TypeChildDatabase: true,
+	"link_preview":    true,
+	"unsupported":     true,
+}
+
+// inlinesChildren lists block types that must be created together with
+// their children, in the same request.
+var inlinesChildren = map[string]bool{
+	TypeColumnList: true,
+	TypeColumn:     true,
+	TypeTable:      true,
+}
+
+func everyBlock(Block) bool { return true }
+
+// ParentID returns the ID of the page or block the block belongs to.
+func (b *Block) ParentID() string {
+	if b.Parent == nil {
+		return ""
+	}
+	if b.Parent.BlockID != "" {
+		return b.Parent.BlockID
+	}
+	return b.Parent.PageID
+}
+
+// isSyncedCopy reports whether the block is a duplicate synced block, whose
+// content belongs to its original and is not copied with it.
+func isSyncedCopy(block Block) bool {
+	return block.ChildrenID() != block.ID
+}
+
+// CopyBlock recreates block, with all of its descendants, under parentID
+// right after afterID, or at the end when afterID is empty. Rich text,
+// colour, checked state and the rest of each payload are kept; the copies get
+// new IDs. If copying fails partway, whatever was created is removed again;
+// if that fails too, the error is a RollbackError naming the partial copy.
+func (c *NotionClient) CopyBlock(ctx context.Context, block Block, parentID, afterID string) (*Block, error) {
+	source := block
+	source.Children = nil
+	if block.HasChildren && !isSyncedCopy(block) {
+		children, err := c.GetBlockTree(ctx, block.ID, everyBlock)
+		if err != nil {
+			return nil, err
+		}
+		source.Children = children
+	}
+
+	created, err := c.appendCopies(ctx, parentID, afterID, []Block{source})
+	if err != nil {
+		if len(created) > 0 {
+			return nil, c.rollback(err, created[0].ID)
+		}
+		return nil, err
+	}
+	return &created[0], nil
+}
+
+// MoveBlock moves block, with all of its descendants, under parentID right
+// after afterID, or to the end when afterID is empty. As the API cannot move
+// blocks, it copies the block with CopyBlock and then deletes the original,
+// so the moved block gets a new ID. If the original cannot be deleted, the
+// copy is removed again.
+func (c *NotionClient) MoveBlock(ctx context.Context, block Block, parentID, afterID string) (*Block, error) {
+	moved, err := c.CopyBlock(ctx, block, parentID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.do(ctx, "DELETE", "/blocks/"+block.ID, nil, nil); err != nil {
+		return nil, c.rollback(err, moved.ID)
+	}
+	return moved, nil
+}
+
+// MoveBlockBefore moves block right before the block before. When before is
+// the first child of its parent, block is moved after it and before is then
+// moved behind block, so both get new IDs. If the last step, deleting the
+// original block, fails, the copy is removed but before keeps its new ID;
+// the returned RollbackError says so.
+func (c *NotionClient) MoveBlockBefore(ctx context.Context, block, before Block) (*Block, error) {
+	parentID := before.ParentID()
+	siblings, err := c.GetAllBlockChildren(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, sibling := range siblings {
+		if sibling.ID == before.ID {
+			index = i
+			break
+		}
+	}
+	switch {
+	case index < 0:
+		return nil, fmt.Errorf("block %s is no longer on the page", before.ID)
+	case index > 0 && siblings[index-1].ID == block.ID:
+		return &block, nil
+	case index > 0:
+		return c.MoveBlock(ctx, block, parentID, siblings[index-1].ID)
+	}
+
+	moved, err := c.CopyBlock(ctx, block, parentID, before.ID)
+	if err != nil {
+		return nil, err
+	}
+	movedBefore, err := c.MoveBlock(ctx, before, parentID, moved.ID)
+	if err != nil {
+		return nil, c.rollback(err, moved.ID)
+	}
+	if err := c.do(ctx, "DELETE", "/blocks/"+block.ID, nil, nil); err != nil {
+		// Only the copy can be undone; the block that was first has been
+		// recreated behind it and stays in its original place.
+		return nil, c.rollback(err, moved.ID, fmt.Sprintf("block %s was recreated in its place as %s", before.ID, movedBefore.ID))
+	}
+	return moved, nil
+}
+
+// rollback undoes a change that failed with err by removing the block it
+// created. Anything that stays behind, including the block itself if it
+// cannot be removed, is reported in a RollbackError; remains lists what was
+// already known to be left.
+func (c *NotionClient) rollback(err error, blockID string, remains ...string) error {
+	if partial, ok := err.(*RollbackError); ok {
+		err, remains = partial.Err, append(partial.Remains, remains...)
+	}
+	if cleanupErr := c.cleanup(blockID); cleanupErr != nil {
+		remains = append(remains, fmt.Sprintf("block %s could not be removed and must be deleted by hand: %v", blockID, cleanupErr))
+	}
+	if len(remains) == 0 {
+		return err
+	}
+	return &RollbackError{Err: err, Remains: remains}
+}
+
+// cleanup archives a block created by a change that is being undone.
+func (c *NotionClient) cleanup(blockID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	return c.do(ctx, "DELETE", "/blocks/"+blockID, nil, nil)
+}
+
+// appendCopies creates copies of blocks and their Children under parentID
+// and returns the created top-level blocks, as far as it got.
+func (c *NotionClient) appendCopies(ctx context.Context, parentID, afterID string, blocks []Block) ([]Block, error) {
+	var created []Block
+	for start := 0; start < len(blocks); start += MaxPageSize {
+		end := start + MaxPageSize
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		var requests []map[string]interface{}
+		for _, block := range blocks[start:end] {
+			request, err := blockRequest(block)
+			if err != nil {
+				return created, err
+			}
+			requests = append(requests, request)
+		}
+		reqBody := map[string]interface{}{"children": requests}
+		if afterID != "" {
+			reqBody["after"] = afterID
+		}
+
+		var result BlockList
+		if err := c.do(ctx, "PATCH", "/blocks/"+parentID+"/children", reqBody, &result); err != nil {
+			return created, err
+		}
+		if len(result.Results) != len(requests) {
+			return append(created, result.Results...), fmt.Errorf("notion created %d of %d blocks", len(result.Results), len(requests))
+		}
+		created = append(created, result.Results...)
+		afterID = created[len(created)-1].ID
+	}
+
+	for i, source := range blocks {
+		if err := c.copyDescendants(ctx, created[i], source); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// copyDescendants creates whatever lies below source that was not already
+// created along with its copy, target.
+func (c *NotionClient) copyDescendants(ctx context.Context, target, source Block) error {
+	if len(source.Children) == 0 || isSyncedCopy(source) {
+		return nil
+	}
+	if !inlinesChildren[source.Type] {
+		_, err := c.appendCopies(ctx, target.ID, "", source.Children)
+		return err
+	}
+
+	// The children were created with target; pair them up by position to
+	// copy what lies below them.
+	children, err := c.GetAllBlockChildren(ctx, target.ID)
+	if err != nil {
+		return err
+	}
+	if len(children) != len(source.Children) {
+		return fmt.Errorf("expected %d children in the copy of block %s, found %d", len(source.Children), source.ID, len(children))
+	}
+	for i := range children {
+		if err := c.copyDescendants(ctx, children[i], source.Children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockRequest turns a block as Notion returned it into the body that
+// creates a copy of it.
+func blockRequest(block Block) (map[string]interface{}, error) {
+	if notCreatable[block.Type] {
+		return nil, fmt.Errorf("block %s is a %s, which cannot be copied", block.ID, block.Type)
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	payload, _ := fields[block.Type].(map[string]interface{})
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	stripReadOnly(payload)
+
+	if inlinesChildren[block.Type] {
+		var children []map[string]interface{}
+		for _, child := range block.Children {
+			request, err := blockRequest(inlineChild(child, block.Type))
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, request)
+		}
+		payload["children"] = children
+	}
+	return map[string]interface{}{
+		"object":   "block",
+		"type":     block.Type,
+		block.Type: payload,
+	}, nil
+}
+
+// inlineChild drops the children of a block sent inline, unless they have to
+// be inline too, as a column's do. Notion accepts two levels of nesting in
+// one request; copyDescendants creates the rest afterwards.
+func inlineChild(child Block, parentType string) Block {
+	if parentType == TypeColumnList {
+		return child
+	}
+	child.Children = nil
+	return child
+}
+
+// stripReadOnly removes the fields Notion fills in on rich text objects,
+// which it does not accept back.
+func stripReadOnly(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, isRichText := v["annotations"]; isRichText {
+			delete(v, "plain_text")
+			delete(v, "href")
+		}
+		for _, field := range v {
+			stripReadOnly(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			stripReadOnly(item)
+		}
+	}
+}
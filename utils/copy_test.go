@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notioncli/notiontest"
+)
+
+func textOf(block map[string]interface{}) string {
+	payload := block[block["type"].(string)].(map[string]interface{})
+	segments, _ := payload["rich_text"].([]interface{})
+	var text string
+	for _, segment := range segments {
+		text += segment.(map[string]interface{})["plain_text"].(string)
+	}
+	return text
+}
+
+func TestCopyBlockKeepsDescendants(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID,
+		notiontest.ToDo("launch", true,
+			notiontest.ToDo("write post", false, notiontest.Paragraph("draft")),
+			notiontest.Block("column_list", nil,
+				notiontest.Block("column", nil, notiontest.ToDo("left", false, notiontest.ToDo("deep", true))),
+				notiontest.Block("column", nil, notiontest.Paragraph("right")),
+			),
+		),
+		notiontest.Paragraph("end"),
+	)
+	client := newFakeClient(server)
+	ctx := context.Background()
+	list, err := client.GetTaskList(ctx, pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	copied, err := client.CopyBlock(ctx, list.Tasks[0].Block, pageID, ids[1])
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if !copied.ToDo.Checked || copied.ToDo.PlainText() != "launch" {
+		t.Errorf("Expected a checked copy of launch, got: %+v", copied.ToDo)
+	}
+
+	children := server.Children(copied.ID)
+	if len(children) != 2 || textOf(children[0]) != "write post" || children[1]["type"] != "column_list" {
+		t.Fatalf("Expected the subtask and the columns to be copied, got: %v", children)
+	}
+	if draft := server.Children(children[0]["id"].(string)); len(draft) != 1 || textOf(draft[0]) != "draft" {
+		t.Errorf("Expected the subtask's paragraph to be copied, got: %v", draft)
+	}
+	columns := server.Children(children[1]["id"].(string))
+	left := server.Children(columns[0]["id"].(string))
+	deep := server.Children(left[0]["id"].(string))
+	if len(deep) != 1 || textOf(deep[0]) != "deep" || deep[0]["to_do"].(map[string]interface{})["checked"] != true {
+		t.Errorf("Expected the task nested in a column to keep its subtask, got: %v", deep)
+	}
+	if page := server.Children(pageID); len(page) != 3 || page[2]["id"] != copied.ID {
+		t.Errorf("Expected the copy at the end of the page, got %d blocks", len(page))
+	}
+}
+
+func TestAddToDoBefore(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID, notiontest.ToDo("first", false, notiontest.ToDo("sub", false)), notiontest.ToDo("second", false))
+	client := newFakeClient(server)
+	ctx := context.Background()
+	list, _ := client.GetTaskList(ctx, pageID)
+
+	if _, err := client.AddToDoBefore(ctx, list.Tasks[0].Block, PlainRichText("urgent"), false); err != ErrFirstChild {
+		t.Fatalf("Expected ErrFirstChild without recreate, got: %v", err)
+	}
+	if got := pageTexts(server, pageID); len(got) != 2 {
+		t.Fatalf("Expected the page untouched, got: %v", got)
+	}
+	if _, err := client.AddToDoBefore(ctx, list.Tasks[0].Block, PlainRichText("urgent"), true); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if _, err := client.AddToDoBefore(ctx, list.Tasks[2].Block, PlainRichText("between"), false); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	var got []string
+	for _, block := range server.Children(pageID) {
+		got = append(got, textOf(block))
+	}
+	if len(got) != 4 || got[0] != "urgent" || got[1] != "first" || got[2] != "between" || got[3] != "second" {
+		t.Errorf("Expected urgent, first, between, second, got: %v", got)
+	}
+	if sub := server.Children(server.Children(pageID)[1]["id"].(string)); len(sub) != 1 || textOf(sub[0]) != "sub" {
+		t.Errorf("Expected the recreated first task to keep its subtask, got: %v", sub)
+	}
+}
+
+func TestAddToDoBeforeRollsBack(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, notiontest.ToDo("first", false, notiontest.Block("child_page", map[string]interface{}{"title": "Notes"})))
+	client := newFakeClient(server)
+	ctx := context.Background()
+	list, _ := client.GetTaskList(ctx, pageID)
+
+	if _, err := client.AddToDoBefore(ctx, list.Tasks[0].Block, PlainRichText("urgent"), true); err == nil {
+		t.Fatalf("Expected an error copying a task with a child page")
+	}
+	if page := server.Children(pageID); len(page) != 1 || page[0]["id"] != ids[0] {
+		t.Errorf("Expected only the original task to remain, got: %v", page)
+	}
+}
+
+func pageTexts(server *notiontest.Server, pageID string) []string {
+	var texts []string
+	for _, block := range server.Children(pageID) {
+		texts = append(texts, textOf(block))
+	}
+	return texts
+}
+
+func TestMoveTask(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("a", false),
+		notiontest.ToDo("b", true, notiontest.ToDo("b1", false)),
+		notiontest.ToDo("c", false),
+	)
+	client := newFakeClient(server)
+	ctx := context.Background()
+
+	moves := []struct {
+		ref      string
+		position int
+		want     []string
+	}{
+		{"1", 3, []string{"b", "c", "a"}},
+		{"3", 1, []string{"a", "b", "c"}},
+		{"2", 1, []string{"b", "a", "c"}},
+	}
+	for _, move := range moves {
+		list, err := client.GetTaskList(ctx, pageID)
+		if err != nil {
+			t.Fatalf("Got error: %v", err)
+		}
+		task, _ := list.ResolveRef(move.ref)
+		if _, err := client.MoveTask(ctx, list, task, move.position); err != nil {
+			t.Fatalf("Got error moving %s to %d: %v", move.ref, move.position, err)
+		}
+		if got := pageTexts(server, pageID); !reflect.DeepEqual(got, move.want) {
+			t.Errorf("Moving %s to %d: expected %v, got %v", move.ref, move.position, move.want, got)
+		}
+	}
+
+	moved := server.Children(pageID)[0]
+	if moved["to_do"].(map[string]interface{})["checked"] != true || len(server.Children(moved["id"].(string))) != 1 {
+		t.Errorf("Expected b to stay checked and keep its subtask, got: %v", moved)
+	}
+}
+
+func TestMoveBlockRollsBack(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	otherID := server.AddPage("Other")
+	ids := server.AppendBlocks(pageID, notiontest.ToDo("a", false))
+	client := newFakeClient(server)
+	list, _ := client.GetTaskList(context.Background(), pageID)
+	server.Fail(notiontest.Failure{Method: "DELETE", Path: "/blocks/" + ids[0], Status: 409, Code: "conflict_error", Times: 10})
+
+	if _, err := client.MoveBlock(context.Background(), list.Tasks[0].Block, otherID, ""); err == nil {
+		t.Fatalf("Expected the failed delete to fail the move")
+	}
+	if len(server.Children(otherID)) != 0 || len(server.Children(pageID)) != 1 {
+		t.Errorf("Expected the copy to be removed and the original kept")
+	}
+}
+
+func TestMoveBlockReportsFailedRollback(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	otherID := server.AddPage("Other")
+	server.AppendBlocks(pageID, notiontest.ToDo("a", false))
+	client := newFakeClient(server)
+	list, _ := client.GetTaskList(context.Background(), pageID)
+	server.Fail(notiontest.Failure{Method: "DELETE", Status: 409, Code: "conflict_error"})
+
+	_, err := client.MoveBlock(context.Background(), list.Tasks[0].Block, otherID, "")
+	var partial *RollbackError
+	var apiErr *APIError
+	if !errors.As(err, &partial) || !errors.As(err, &apiErr) || apiErr.Code != "conflict_error" {
+		t.Fatalf("Expected a rollback error wrapping the API error, got: %v", err)
+	}
+	copies := server.Children(otherID)
+	if len(copies) != 1 || !strings.Contains(err.Error(), copies[0]["id"].(string)) {
+		t.Errorf("Expected the error to name the copy left behind, got: %v", err)
+	}
+}
+
+func TestMoveBlockBeforeReportsPartialUndo(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, notiontest.ToDo("a", false), notiontest.ToDo("b", false))
+	client := newFakeClient(server)
+	list, _ := client.GetTaskList(context.Background(), pageID)
+	server.Fail(notiontest.Failure{Method: "DELETE", Path: "/blocks/" + ids[1], Status: 409, Code: "conflict_error", Times: 10})
+
+	_, err := client.MoveBlockBefore(context.Background(), list.Tasks[1].Block, list.Tasks[0].Block)
+	var partial *RollbackError
+	if !errors.As(err, &partial) || !strings.Contains(err.Error(), ids[0]) {
+		t.Fatalf("Expected an error saying the first task was recreated, got: %v", err)
+	}
+	if got := pageTexts(server, pageID); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Expected the order to be restored, got %v", got)
+	}
+}
@@ -0,0 +1,438 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const editFileHeader = `<!--
+  Edit the tasks below, then save and close the editor. The changes are shown
+  for confirmation before anything is applied.
+
+  Only top-level tasks placed directly on the page can be edited here.
+  Subtasks move with their parent task, and tasks inside toggles or columns
+  are left alone.
+
+  Reorder lines to move tasks, delete a line to delete its task, tick or untick
+  the box to check or uncheck it, and add "- [ ] text" lines for new tasks.
+  Keep the id comment at the end of each existing task. Text may use inline
+  Markdown; \n stands for a line break within a task, and \\ for a backslash
+  in code.
+-->
+
+`
+
+var (
+	editLinePattern = regexp.MustCompile(`^[-*+]\s+\[([ xX])\]\s?(.*?)\s*(?:<!--\s*id:\s*([0-9a-fA-F-]+)\s*-->)?\s*$`)
+	editIDPattern   = regexp.MustCompile(`<!--\s*id:`)
+)
+
+// EditEntry is a task line of an edit-all file.
+type EditEntry struct {
+	Line     int
+	ID       string // empty for tasks added in the editor
+	Checked  bool
+	Markdown string
+}
+
+// RenderEditFile writes tasks as a Markdown checklist for edit-all, each line
+// ending in a comment with the task's block ID. Line breaks in a task's text
+// are written as \n, and backslashes in code spans are doubled.
+func RenderEditFile(tasks []*Task) string {
+	var out strings.Builder
+	out.WriteString(editFileHeader)
+	for _, task := range tasks {
+		box := " "
+		if task.Block.ToDo.Checked {
+			box = "x"
+		}
+		markdown := escapeLineBreaks(RenderMarkdown(task.Block.ToDo.RichText))
+		fmt.Fprintf(&out, "- [%s] %s <!-- id:%s -->\n", box, markdown, task.Block.ID)
+	}
+	return out.String()
+}
+
+// ParseEditFile reads back a file written by RenderEditFile. Blank lines and
+// HTML comments are ignored; any other line must be a checklist item.
+func ParseEditFile(content string) ([]EditEntry, error) {
+	var entries []EditEntry
+	inComment := false
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case inComment:
+			inComment = !strings.Contains(line, "-->")
+			continue
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "<!--"):
+			inComment = !strings.Contains(line, "-->")
+			continue
+		}
+
+		match := editLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d is not a task: %q", i+1, line)
+		}
+		if match[3] == "" && editIDPattern.MatchString(match[2]) {
+			return nil, fmt.Errorf("line %d has a damaged id comment: %q", i+1, line)
+		}
+		if strings.TrimSpace(match[2]) == "" {
+			return nil, fmt.Errorf("line %d has no text; delete the line to delete the task", i+1)
+		}
+		entries = append(entries, EditEntry{
+			Line:     i + 1,
+			ID:       match[3],
+			Checked:  match[1] != " ",
+			Markdown: unescapeLineBreaks(match[2]),
+		})
+	}
+	return entries, nil
+}
+
+// escapeLineBreaks writes the line breaks in rendered Markdown as \n so that
+// a task fits on one line. ParseMarkdown takes code spans literally, so the
+// backslashes in them are doubled to tell a \n typed in code from a break.
+func escapeLineBreaks(markdown string) string {
+	var out strings.Builder
+	for i := 0; i < len(markdown); i++ {
+		switch {
+		case markdown[i] == '\\' && i+1 < len(markdown) && markdown[i+1] != '\n':
+			out.WriteString(markdown[i : i+2])
+			i++
+		case markdown[i] == '`':
+			if end := strings.IndexByte(markdown[i+1:], '`'); end > 0 {
+				out.WriteString("`" + codeLineBreakEscaper.Replace(markdown[i+1:i+1+end]) + "`")
+				i += end + 1
+				continue
+			}
+			out.WriteByte('`')
+		case markdown[i] == '\n':
+			out.WriteString(`\n`)
+		default:
+			out.WriteByte(markdown[i])
+		}
+	}
+	return out.String()
+}
+
+// unescapeLineBreaks is the inverse of escapeLineBreaks. Outside code, other
+// backslash escapes are kept for ParseMarkdown, so an escaped backslash
+// followed by "n" stays literal.
+func unescapeLineBreaks(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], `\n`):
+			out.WriteByte('\n')
+			i++
+		case line[i] == '\\' && i+1 < len(line):
+			out.WriteString(line[i : i+2])
+			i++
+		case line[i] == '`':
+			if end := strings.IndexByte(line[i+1:], '`'); end > 0 {
+				out.WriteString("`" + codeLineBreakUnescaper.Replace(line[i+1:i+1+end]) + "`")
+				i += end + 1
+				continue
+			}
+			out.WriteByte('`')
+		default:
+			out.WriteByte(line[i])
+		}
+	}
+	return out.String()
+}
+
+var (
+	codeLineBreakEscaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	codeLineBreakUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+// editStep is one task of the edited list, in its final order.
+type editStep struct {
+	entry EditEntry
+	task  *Task // nil for a task added in the editor
+	text  []RichText
+	edit  bool // the text changed
+	check bool // the checked state changed
+	move  bool // the task has to be recreated in a new place
+}
+
+// EditPlan is the set of API operations that turns a page's tasks into an
+// edited checklist: deletions, text edits, checks, moves and additions.
+// Moves are kept to a minimum by leaving the longest run of tasks that are
+// still in their original order where they are.
+type EditPlan struct {
+	pageID    string
+	steps     []*editStep
+	deletes   []*Task
+	topAnchor string // block to insert after to reach the top of the list
+}
+
+// EditableTasks returns the tasks edit-all lists: the to-dos among a page's
+// direct children. Given the blocks from GetTaskTree, they are numbered as
+// list numbers them.
+func EditableTasks(pageID string, children []Block) []*Task {
+	direct := map[string]bool{}
+	for _, block := range children {
+		direct[block.ID] = true
+	}
+	var tasks []*Task
+	for _, task := range NewTaskList(pageID, children).Roots {
+		if direct[task.Block.ID] {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// PlanEdits compares the EditableTasks among the page's children with the
+// entries of an edited file.
+func PlanEdits(pageID string, children []Block, entries []EditEntry) (*EditPlan, error) {
+	tasks := EditableTasks(pageID, children)
+	byID := map[string]int{}
+	for i, task := range tasks {
+		byID[normalizeID(task.Block.ID)] = i
+	}
+
+	plan := &EditPlan{pageID: pageID}
+	kept := map[int]bool{}
+	var order []int // original index of each kept task, in edited order
+	for _, entry := range entries {
+		step := &editStep{entry: entry, text: ParseMarkdown(entry.Markdown)}
+		if entry.ID != "" {
+			index, ok := byID[normalizeID(entry.ID)]
+			if !ok {
+				return nil, fmt.Errorf("line %d refers to task %s, which is not on the page", entry.Line, entry.ID)
+			}
+			if kept[index] {
+				return nil, fmt.Errorf("line %d repeats task %s", entry.Line, entry.ID)
+			}
+			kept[index] = true
+			order = append(order, index)
+			step.task = tasks[index]
+			step.edit = entry.Markdown != strings.TrimRight(RenderMarkdown(step.task.Block.ToDo.RichText), " ")
+			step.check = entry.Checked != step.task.Block.ToDo.Checked
+		}
+		plan.steps = append(plan.steps, step)
+	}
+	for i, task := range tasks {
+		if !kept[i] {
+			plan.deletes = append(plan.deletes, task)
+		}
+	}
+
+	// Notion can only insert after a block. When the first task is also the
+	// first block on the page, anything that ends up above it is inserted
+	// after it, and it is then moved down or deleted.
+	firstIsTop := len(tasks) > 0 && len(children) > 0 && children[0].ID == tasks[0].Block.ID
+	if len(tasks) > 0 && !firstIsTop {
+		for i, block := range children {
+			if block.ID == tasks[0].Block.ID {
+				plan.topAnchor = children[i-1].ID
+			}
+		}
+	}
+	pinFirst := firstIsTop && len(plan.steps) > 0 && plan.steps[0].task != tasks[0]
+	if pinFirst {
+		plan.topAnchor = tasks[0].Block.ID
+	}
+
+	stable := longestIncreasing(order)
+	k := 0
+	for _, step := range plan.steps {
+		if step.task == nil {
+			continue
+		}
+		step.move = !stable[k] || pinFirst && step.task == tasks[0]
+		k++
+	}
+	return plan, nil
+}
+
+// label names a task of the edited list in Describe.
+func (s *editStep) label() string {
+	if s.task == nil {
+		return fmt.Sprintf("new %q", PlainText(s.text))
+	}
+	return fmt.Sprintf("%s %q", s.task.Number, PlainText(s.text))
+}
+
+// longestIncreasing marks the elements of seq that form one of its longest
+// increasing subsequences.
+func longestIncreasing(seq []int) []bool {
+	tails := []int{} // tails[l] is the index in seq ending the best run of length l+1
+	prev := make([]int, len(seq))
+	for i, value := range seq {
+		l := sort.Search(len(tails), func(j int) bool { return seq[tails[j]] >= value })
+		prev[i] = -1
+		if l > 0 {
+			prev[i] = tails[l-1]
+		}
+		if l == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[l] = i
+		}
+	}
+	marked := make([]bool, len(seq))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			marked[i] = true
+		}
+	}
+	return marked
+}
+
+// Describe lists the planned operations, one line each, in the order they
+// are applied. Tasks are given by the numbers list shows.
+func (p *EditPlan) Describe() []string {
+	var lines []string
+	for _, step := range p.steps {
+		if step.task == nil {
+			continue
+		}
+		number, old := step.task.Number, step.task.Block.ToDo.PlainText()
+		if step.edit {
+			lines = append(lines, fmt.Sprintf("edit    %s %q -> %q", number, old, PlainText(step.text)))
+		}
+		if step.check && step.entry.Checked {
+			lines = append(lines, fmt.Sprintf("check   %s %q", number, old))
+		} else if step.check {
+			lines = append(lines, fmt.Sprintf("uncheck %s %q", number, old))
+		}
+	}
+	for i, step := range p.steps {
+		if step.task != nil && !step.move {
+			continue
+		}
+		place := "at the top"
+		if i > 0 {
+			place = "after " + p.steps[i-1].label()
+		}
+		if step.task == nil {
+			lines = append(lines, fmt.Sprintf("add     %q %s", PlainText(step.text), place))
+		} else {
+			lines = append(lines, fmt.Sprintf("move    %s %q %s", step.task.Number, PlainText(step.text), place))
+		}
+	}
+	for _, task := range p.deletes {
+		lines = append(lines, fmt.Sprintf("delete  %s %q", task.Number, task.Block.ToDo.PlainText()))
+	}
+	return lines
+}
+
+// ApplyEdits carries out a plan: first text and checked changes in place,
+// then moves and additions from the top of the list down, each placed after
+// the task before it, and finally deletions. It stops at the first error.
+func (c *NotionClient) ApplyEdits(ctx context.Context, plan *EditPlan) error {
+	for _, step := range plan.steps {
+		if step.task == nil || !step.edit && !step.check {
+			continue
+		}
+		block := step.task.Block
+		todo := *block.ToDo
+		todo.Checked = step.entry.Checked
+		block.ToDo = &todo
+		var updated *Block
+		var err error
+		if step.edit {
+			updated, err = c.SetToDoText(ctx, block, step.text)
+		} else {
+			updated, err = c.SetToDoChecked(ctx, block, step.entry.Checked)
+		}
+		if err != nil {
+			return fmt.Errorf("error updating task %s: %v", step.task.Number, err)
+		}
+		if updated.ID != "" {
+			step.task.Block = *updated
+		}
+	}
+
+	anchor := plan.topAnchor
+	for _, step := range plan.steps {
+		switch {
+		case step.task == nil:
+			created, err := c.AddToDoAfter(ctx, plan.pageID, anchor, step.text)
+			if err != nil {
+				return fmt.Errorf("error adding %q: %v", step.entry.Markdown, err)
+			}
+			anchor = created.ID
+		case step.move:
+			moved, err := c.MoveBlock(ctx, step.task.Block, plan.pageID, anchor)
+			if err != nil {
+				return fmt.Errorf("error moving task %s: %v", step.task.Number, err)
+			}
+			anchor = moved.ID
+		default:
+			anchor = step.task.Block.ID
+		}
+	}
+
+	for _, task := range plan.deletes {
+		if err := c.DeleteToDo(ctx, task.Block); err != nil {
+			return fmt.Errorf("error deleting task %s: %v", task.Number, err)
+		}
+	}
+	return nil
+}
+
+// Renumbers reports whether the plan adds, moves or deletes tasks, which
+// changes the numbers of tasks it does not touch.
+func (p *EditPlan) Renumbers() bool {
+	if len(p.deletes) > 0 {
+		return true
+	}
+	for _, step := range p.steps {
+		if step.task == nil || step.move {
+			return true
+		}
+	}
+	return false
+}
+
+// Updated returns the tasks whose text or checked state the plan changes.
+// After ApplyEdits their blocks hold the updated content.
+func (p *EditPlan) Updated() []*Task {
+	var tasks []*Task
+	for _, step := range p.steps {
+		if step.task != nil && (step.edit || step.check) {
+			tasks = append(tasks, step.task)
+		}
+	}
+	return tasks
+}
+
+// sameToDo compares the text and checked state of two versions of a to-do.
+// Notion only keeps edit times to the minute, so they miss quick edits.
+func sameToDo(a, b Block) bool {
+	return a.ToDo != nil && b.ToDo != nil && a.ToDo.Checked == b.ToDo.Checked &&
+		RenderMarkdown(a.ToDo.RichText) == RenderMarkdown(b.ToDo.RichText)
+}
+
+// CheckUnedited verifies that none of the EditableTasks in before was
+// edited or deleted by the time after was fetched, such as while the user
+// had the edit-all file open.
+func CheckUnedited(pageID string, before, after []Block) error {
+	current := map[string]Block{}
+	for _, block := range after {
+		current[block.ID] = block
+	}
+	for _, task := range EditableTasks(pageID, before) {
+		block, ok := current[task.Block.ID]
+		switch {
+		case !ok:
+			return &StaleViewError{Ref: task.Number, Reason: fmt.Sprintf("(%q) was deleted while the editor was open", task.Block.ToDo.PlainText())}
+		case block.LastEditedTime != task.Block.LastEditedTime || !sameToDo(block, task.Block):
+			return &StaleViewError{Ref: task.Number, Reason: fmt.Sprintf("(%q) was edited while the editor was open", task.Block.ToDo.PlainText())}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notioncli/notiontest"
+)
+
+func TestParseEditFile(t *testing.T) {
+	tasks := []*Task{
+		{Block: Block{ID: "aaaa-1", ToDo: &ToDo{RichText: ParseMarkdown("write **report**")}}},
+		{Block: Block{ID: "bbbb-2", ToDo: &ToDo{Checked: true, RichText: PlainRichText("2 * 3")}}},
+	}
+	entries, err := ParseEditFile(RenderEditFile(tasks) + "\n* [X] new task\n")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	want := []EditEntry{
+		{ID: "aaaa-1", Markdown: "write **report**"},
+		{ID: "bbbb-2", Checked: true, Markdown: `2 \* 3`},
+		{Checked: true, Markdown: "new task"},
+	}
+	for i := range entries {
+		entries[i].Line = 0
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Expected %+v, got %+v", want, entries)
+	}
+
+	for _, content := range []string{
+		"just a note",
+		"- [ ] text <!-- id:",
+		"- [ ]  <!-- id:aaaa -->",
+	} {
+		if _, err := ParseEditFile(content); err == nil {
+			t.Errorf("Expected an error for %q", content)
+		}
+	}
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	got := longestIncreasing([]int{2, 0, 1, 4, 3})
+	want := []bool{false, true, true, false, true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// editLines rewrites the edit-all file for a page as the user would.
+func editLines(t *testing.T, client *NotionClient, pageID string, edit func(lines map[string]string) []string) (*EditPlan, []Block) {
+	t.Helper()
+	children, err := client.GetTaskTree(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	lines := map[string]string{}
+	for _, line := range strings.Split(RenderEditFile(EditableTasks(pageID, children)), "\n") {
+		if match := editLinePattern.FindStringSubmatch(line); match != nil {
+			lines[match[2]] = line
+		}
+	}
+	entries, err := ParseEditFile(strings.Join(edit(lines), "\n"))
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	plan, err := PlanEdits(pageID, children, entries)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	return plan, children
+}
+
+func TestApplyEdits(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("a", false),
+		notiontest.ToDo("b", false, notiontest.ToDo("b1", false)),
+		notiontest.Paragraph("note"),
+		notiontest.ToDo("c", false),
+		notiontest.ToDo("d", true),
+	)
+	client := newFakeClient(server)
+
+	plan, _ := editLines(t, client, pageID, func(lines map[string]string) []string {
+		return []string{
+			"- [ ] new",
+			lines["b"],
+			strings.Replace(lines["c"], "[ ] c", "[x] **c!**", 1),
+			lines["a"],
+		}
+	})
+	want := []string{
+		`edit    3 "c" -> "c!"`,
+		`check   3 "c"`,
+		`add     "new" at the top`,
+		`move    1 "a" after 3 "c!"`,
+		`delete  4 "d"`,
+	}
+	if got := plan.Describe(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected plan %q, got %q", want, got)
+	}
+	if err := client.ApplyEdits(context.Background(), plan); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+
+	if got, want := pageTexts(server, pageID), []string{"new", "b", "note", "c!", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	page := server.Children(pageID)
+	if len(server.Children(page[1]["id"].(string))) != 1 {
+		t.Errorf("Expected b to keep its subtask")
+	}
+	if page[3]["to_do"].(map[string]interface{})["checked"] != true {
+		t.Errorf("Expected c to be checked, got: %v", page[3])
+	}
+
+	unchanged, _ := editLines(t, client, pageID, func(lines map[string]string) []string {
+		return []string{lines["new"], lines["b"], lines["**c!**"], lines["a"]}
+	})
+	if got := unchanged.Describe(); len(got) != 0 {
+		t.Errorf("Expected no changes, got %q", got)
+	}
+}
+
+func TestApplyEditsBelowHeading(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID, notiontest.Heading(1, "Today"), notiontest.ToDo("a", false), notiontest.ToDo("b", false))
+	client := newFakeClient(server)
+
+	plan, _ := editLines(t, client, pageID, func(lines map[string]string) []string {
+		return []string{lines["b"], "- [ ] new", lines["a"]}
+	})
+	if got := plan.Describe(); len(got) != 2 {
+		t.Errorf("Expected one move and one addition, got %q", got)
+	}
+	if err := client.ApplyEdits(context.Background(), plan); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if got, want := pageTexts(server, pageID), []string{"Today", "b", "new", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckUnedited(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID, notiontest.ToDo("a", false))
+	client := newFakeClient(server)
+	ctx := context.Background()
+
+	_, before := editLines(t, client, pageID, func(lines map[string]string) []string { return nil })
+	if _, err := client.SetToDoChecked(ctx, before[0], true); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	after, _ := client.GetAllBlockChildren(ctx, pageID)
+	if err := CheckUnedited(pageID, before, after); err == nil {
+		t.Errorf("Expected an error for a task edited meanwhile")
+	}
+	if err := CheckUnedited(pageID, after, after); err != nil {
+		t.Errorf("Got error: %v", err)
+	}
+}
+
+func TestEditFileKeepsLineBreaks(t *testing.T) {
+	text := []RichText{
+		textSegment("line one\nline ", Annotation{}, ""),
+		textSegment("two\nthree", Annotation{Bold: true}, ""),
+		textSegment(` C:\new`, Annotation{}, ""),
+	}
+	tasks := []*Task{{Block: Block{Object: "block", ID: "aaaa-1", Type: TypeToDo, ToDo: &ToDo{RichText: text}}}}
+	content := RenderEditFile(tasks)
+	if lines := strings.Count(content, "<!-- id:"); lines != 1 || !strings.Contains(content, `line one\nline **two\nthree**`) {
+		t.Fatalf("Expected the task on one line with escaped line breaks, got:\n%s", content)
+	}
+
+	entries, err := ParseEditFile(content)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Markdown != RenderMarkdown(text) {
+		t.Fatalf("Expected the Markdown with its line breaks back, got: %+v", entries)
+	}
+	if got := PlainText(ParseMarkdown(entries[0].Markdown)); got != PlainText(text) {
+		t.Errorf("Expected %q after the round trip, got %q", PlainText(text), got)
+	}
+
+	plan, err := PlanEdits("pageID", []Block{tasks[0].Block}, entries)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if got := plan.Describe(); len(got) != 0 {
+		t.Errorf("Expected no changes for an untouched multi-line task, got %q", got)
+	}
+}
+
+func TestEditFileKeepsBackslashesInCode(t *testing.T) {
+	text := []RichText{
+		textSegment("call ", Annotation{}, ""),
+		textSegment(`printf("\n")`, Annotation{Code: true}, ""),
+		textSegment(" then ", Annotation{}, ""),
+		textSegment("a\nb\\", Annotation{Code: true}, ""),
+	}
+	block := Block{Object: "block", ID: "aaaa-1", Type: TypeToDo, ToDo: &ToDo{RichText: text}}
+	content := RenderEditFile([]*Task{{Block: block}})
+	if !strings.Contains(content, "`printf(\"\\\\n\")` then `a\\nb\\\\`") {
+		t.Fatalf("Expected backslashes in code to be doubled, got:\n%s", content)
+	}
+
+	entries, err := ParseEditFile(content)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	plan, err := PlanEdits("pageID", []Block{block}, entries)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if got := plan.Describe(); len(got) != 0 {
+		t.Errorf("Expected no changes for an untouched task with code, got %q", got)
+	}
+}
+
+func TestEditPlanUsesListNumbers(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID,
+		notiontest.Toggle("Later", notiontest.ToDo("hidden", false)),
+		notiontest.ToDo("a", false, notiontest.ToDo("a1", false)),
+		notiontest.ToDo("b", false),
+	)
+	client := newFakeClient(server)
+
+	plan, _ := editLines(t, client, pageID, func(lines map[string]string) []string {
+		if _, ok := lines["hidden"]; ok {
+			t.Errorf("Expected tasks inside toggles to be left out of the file")
+		}
+		return []string{strings.Replace(lines["b"], "[ ]", "[x]", 1), lines["a"]}
+	})
+	want := []string{`check   3 "b"`, `move    3 "b" at the top`}
+	if got := plan.Describe(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected plan %q, got %q", want, got)
+	}
+}
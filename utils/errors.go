@@ -0,0 +1,109 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error codes returned by the Notion API, see
+// https://developers.notion.com/reference/status-codes
+const (
+	CodeInvalidJSON                   = "invalid_json"
+	CodeInvalidRequestURL             = "invalid_request_url"
+	CodeInvalidRequest                = "invalid_request"
+	CodeValidationError               = "validation_error"
+	CodeMissingVersion                = "missing_version"
+	CodeUnauthorized                  = "unauthorized"
+	CodeRestrictedResource            = "restricted_resource"
+	CodeObjectNotFound                = "object_not_found"
+	CodeConflictError                 = "conflict_error"
+	CodeRateLimited                   = "rate_limited"
+	CodeInternalServerError           = "internal_server_error"
+	CodeServiceUnavailable            = "service_unavailable"
+	CodeDatabaseConnectionUnavailable = "database_connection_unavailable"
+	CodeGatewayTimeout                = "gateway_timeout"
+)
+
+// APIError is the error object Notion sends with every unsuccessful response.
+// Callers can inspect it with errors.As.
+type APIError struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("notion API error %d", e.Status)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request_id: " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// RollbackError is returned when a change failed and could only partly be
+// undone. Err is why the change failed, and Unwrap returns it so callers
+// still see the original error; Remains describes what is left on the page.
+type RollbackError struct {
+	Err     error
+	Remains []string
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("%v; the change was only partly undone: %s", e.Err, strings.Join(e.Remains, "; "))
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError decodes Notion's error object from a response body. Bodies that
+// are not a Notion error, such as a proxy's HTML page, still produce an
+// APIError carrying the status and a code inferred from it.
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	apiErr.Status = status
+	if apiErr.Code == "" {
+		apiErr.Code = codeForStatus(status)
+	}
+	return apiErr
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeRestrictedResource
+	case http.StatusNotFound:
+		return CodeObjectNotFound
+	case http.StatusConflict:
+		return CodeConflictError
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusInternalServerError:
+		return CodeInternalServerError
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeGatewayTimeout
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskFilter selects the tasks `list` shows. Every field that is set must
+// match; the zero value matches every task.
+type TaskFilter struct {
+	Open         bool
+	Done         bool
+	Pattern      *regexp.Regexp // matched against the plain text
+	EditedSince  time.Time
+	EditedBefore time.Time
+	CreatedSince time.Time
+	Color        string // a Notion colour, e.g. "red" or "red_background"
+}
+
+// Match reports whether a task passes the filter.
+func (f TaskFilter) Match(task *Task) bool {
+	todo := task.Block.ToDo
+	switch {
+	case f.Open && todo.Checked, f.Done && !todo.Checked:
+		return false
+	case f.Pattern != nil && !f.Pattern.MatchString(todo.PlainText()):
+		return false
+	case f.Color != "" && !hasColor(todo, f.Color):
+		return false
+	}
+	if !f.EditedSince.IsZero() || !f.EditedBefore.IsZero() {
+		edited, err := time.Parse(time.RFC3339, task.Block.LastEditedTime)
+		if err != nil || edited.Before(f.EditedSince) || !f.EditedBefore.IsZero() && !edited.Before(f.EditedBefore) {
+			return false
+		}
+	}
+	if !f.CreatedSince.IsZero() {
+		created, err := time.Parse(time.RFC3339, task.Block.CreatedTime)
+		if err != nil || created.Before(f.CreatedSince) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the tasks that pass the filter, in order.
+func (f TaskFilter) Filter(tasks []*Task) []*Task {
+	var matched []*Task
+	for _, task := range tasks {
+		if f.Match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+// hasColor reports whether the to-do or any part of its text has colour.
+// "default" matches to-dos without any colour.
+func hasColor(todo *ToDo, colour string) bool {
+	blockColor := todo.Color
+	if blockColor == "" {
+		blockColor = "default"
+	}
+	if colour == "default" {
+		if blockColor != "default" {
+			return false
+		}
+		for _, segment := range todo.RichText {
+			if segment.Annotations.Color != "" && segment.Annotations.Color != "default" {
+				return false
+			}
+		}
+		return true
+	}
+	if blockColor == colour {
+		return true
+	}
+	for _, segment := range todo.RichText {
+		if segment.Annotations.Color == colour {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseColor checks a colour name given on the command line.
+func ParseColor(name string) (string, error) {
+	colour := strings.ToLower(strings.TrimSpace(name))
+	if _, ok := notionColors[colour]; ok || colour == "default" {
+		return colour, nil
+	}
+	var names []string
+	for known := range notionColors {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("unknown colour %q; use default or one of %s", name, strings.Join(names, ", "))
+}
+
+var relativeTimePattern = regexp.MustCompile(`^(\d+)\s*(m|min|h|d|w)$`)
+
+var timeLayouts = []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// ParseTime reads a time given on the command line. It may be relative to
+// now, as in "90m", "24h", "7d" or "2w" ago, "today" or "yesterday" (from
+// midnight), or an absolute date or date and time in loc, such as
+// "2023-05-01" or "2023-05-01 09:30". RFC 3339 times carry their own offset.
+func ParseTime(input string, now time.Time, loc *time.Location) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	now = now.In(loc)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch s {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	}
+
+	if match := relativeTimePattern.FindStringSubmatch(s); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %v", input, err)
+		}
+		switch match[2] {
+		case "m", "min":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		}
+		return now.AddDate(0, 0, -7*n), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(s), loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration such as 24h or 7d, today, yesterday, or a date such as 2023-05-01 or 2023-05-01 09:30", input)
+}
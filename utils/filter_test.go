@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	now := time.Date(2023, 5, 10, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"24h", now.Add(-24 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"1h30m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"today", time.Date(2023, 5, 10, 0, 0, 0, 0, loc)},
+		{"yesterday", time.Date(2023, 5, 9, 0, 0, 0, 0, loc)},
+		{"2023-05-01", time.Date(2023, 5, 1, 0, 0, 0, 0, loc)},
+		{"2023-05-01 09:30", time.Date(2023, 5, 1, 9, 30, 0, 0, loc)},
+		{"2023-05-01T09:30:00Z", time.Date(2023, 5, 1, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := ParseTime(test.input, now, loc)
+		if err != nil {
+			t.Errorf("ParseTime(%q): got error: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("ParseTime(%q): expected %v, got %v", test.input, test.want, got)
+		}
+	}
+	if _, err := ParseTime("last week", now, loc); err == nil {
+		t.Errorf("Expected an error for an unknown time")
+	}
+}
+
+func TestTaskFilter(t *testing.T) {
+	red := RichText{PlainText: "urgent", Annotations: Annotation{Color: "red"}}
+	task := func(text string, checked bool, edited string, rich ...RichText) *Task {
+		if len(rich) == 0 {
+			rich = PlainRichText(text)
+		}
+		return &Task{Number: text, Block: Block{
+			CreatedTime:    "2023-05-01T08:00:00.000Z",
+			LastEditedTime: edited,
+			ToDo:           &ToDo{Checked: checked, RichText: rich},
+		}}
+	}
+	tasks := []*Task{
+		task("write report", false, "2023-05-09T10:00:00.000Z"),
+		task("review PR", true, "2023-05-10T10:00:00.000Z"),
+		task("urgent", false, "2023-05-10T11:00:00.000Z", red),
+	}
+	day := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter TaskFilter
+		want   []string
+	}{
+		{"none", TaskFilter{}, []string{"write report", "review PR", "urgent"}},
+		{"open", TaskFilter{Open: true}, []string{"write report", "urgent"}},
+		{"done", TaskFilter{Done: true}, []string{"review PR"}},
+		{"grep", TaskFilter{Pattern: regexp.MustCompile(`(?i)^RE`)}, []string{"review PR"}},
+		{"edited since", TaskFilter{Open: true, EditedSince: day}, []string{"urgent"}},
+		{"edited before", TaskFilter{EditedBefore: day}, []string{"write report"}},
+		{"created since", TaskFilter{CreatedSince: day}, nil},
+		{"colour", TaskFilter{Color: "red"}, []string{"urgent"}},
+		{"default colour", TaskFilter{Color: "default"}, []string{"write report", "review PR"}},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, task := range test.filter.Filter(tasks) {
+			got = append(got, task.Number)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"notioncli/notiontest"
+)
+
+func newFakeClient(server *notiontest.Server) *NotionClient {
+	return NewNotionClient("fakeKey",
+		WithBaseURL(server.URL),
+		WithRateLimit(0, 0),
+		WithRetryPolicy(fastRetries),
+	)
+}
+
+func TestEndToEndAgainstFakeServer(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID,
+		notiontest.Heading(2, "Today"),
+		notiontest.ToDo("write report", false),
+		notiontest.Paragraph("notes"),
+		notiontest.ToDo("review PR", false),
+	)
+	client := newFakeClient(server)
+	ctx := context.Background()
+
+	if err := client.AddNewToDoItem(ctx, pageID, "ship it"); err != nil {
+		t.Fatalf("Got error adding a task: %v", err)
+	}
+	if err := client.MarkToDoBlockChecked(ctx, pageID, 2); err != nil {
+		t.Fatalf("Got error checking a task: %v", err)
+	}
+	if err := client.DeleteToDoBlock(ctx, pageID, 1); err != nil {
+		t.Fatalf("Got error deleting a task: %v", err)
+	}
+
+	list, err := client.GetTaskList(ctx, pageID)
+	if err != nil {
+		t.Fatalf("Got error listing tasks: %v", err)
+	}
+	if len(list.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got: %d", len(list.Tasks))
+	}
+	if text := list.Tasks[0].Block.ToDo.PlainText(); text != "review PR" || !list.Tasks[0].Block.ToDo.Checked {
+		t.Errorf("Expected a checked \"review PR\" first, got: %q checked=%v", text, list.Tasks[0].Block.ToDo.Checked)
+	}
+	if text := list.Tasks[1].Block.ToDo.PlainText(); text != "ship it" {
+		t.Errorf("Expected \"ship it\" last, got: %q", text)
+	}
+}
+
+func TestEndToEndPaginationAndArchivedBlocks(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	var blocks []map[string]interface{}
+	for i := 1; i <= 150; i++ {
+		blocks = append(blocks, notiontest.ToDo(fmt.Sprintf("task %d", i), false))
+	}
+	ids := server.AppendBlocks(pageID, blocks[:100]...)
+	server.AppendBlocks(pageID, blocks[100:]...)
+	server.Archive(ids[0])
+
+	client := newFakeClient(server)
+	list, err := client.GetTaskList(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(list.Tasks) != 149 {
+		t.Errorf("Expected 149 tasks, got: %d", len(list.Tasks))
+	}
+	if got := server.RequestCount("GET", "/blocks/"+pageID+"/children"); got != 2 {
+		t.Errorf("Expected 2 page requests, got: %d", got)
+	}
+}
+
+func TestEndToEndRetriesInjectedFailures(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID, notiontest.ToDo("task", false))
+	server.Fail(notiontest.Failure{Method: "GET", Status: http.StatusTooManyRequests, Times: 1})
+	server.Fail(notiontest.Failure{Method: "GET", Status: http.StatusInternalServerError, Times: 1})
+
+	client := newFakeClient(server)
+	blocks, err := client.GetBlocks(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(blocks) != 1 {
+		t.Errorf("Expected 1 block, got: %d", len(blocks))
+	}
+}
@@ -0,0 +1,350 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"strings"
+)
+
+// PlainRichText returns text as a single unformatted rich text segment.
+func PlainRichText(text string) []RichText {
+	return []RichText{textSegment(text, Annotation{}, "")}
+}
+
+// ParseMarkdown turns a line of inline Markdown into rich text segments. It
+// understands **bold**, *italic*, ~~strikethrough~~, `code` and
+// [label](url), which may be nested, and backslash escapes. Markers without a
+// closing partner are kept as literal text.
+func ParseMarkdown(text string) []RichText {
+	segments := mergeSegments(parseInline(text, Annotation{}, ""))
+	if len(segments) == 0 {
+		return PlainRichText(text)
+	}
+	return segments
+}
+
+func parseInline(s string, ann Annotation, link string) []RichText {
+	var segments []RichText
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, textSegment(literal.String(), ann, link))
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\\*~`[]()_", s[i+1]) >= 0:
+			literal.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				flush()
+				code := ann
+				code.Code = true
+				segments = append(segments, textSegment(s[i+1:i+1+end], code, link))
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "~~"):
+			marker := s[i : i+2]
+			if end := closingMarker(s[i+2:], marker); end > 0 && flanked(s[i+2:i+2+end]) {
+				flush()
+				inner := ann
+				if marker == "**" {
+					inner.Bold = true
+				} else {
+					inner.Strikethrough = true
+				}
+				segments = append(segments, parseInline(s[i+2:i+2+end], inner, link)...)
+				i += end + 4
+				continue
+			}
+
+		case s[i] == '*':
+			if end := closingMarker(s[i+1:], "*"); end > 0 && flanked(s[i+1:i+1+end]) {
+				flush()
+				inner := ann
+				inner.Italic = true
+				segments = append(segments, parseInline(s[i+1:i+1+end], inner, link)...)
+				i += end + 2
+				continue
+			}
+
+		case s[i] == '[' && link == "":
+			if label, url, n := parseLink(s[i:]); n > 0 {
+				flush()
+				segments = append(segments, parseInline(label, ann, url)...)
+				i += n
+				continue
+			}
+		}
+		literal.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return segments
+}
+
+// closingMarker returns the index in s of the marker that closes one just
+// before s, skipping escaped characters, code spans and emphasis opened in
+// between, or -1. A run of stars right after text closes emphasis, so that
+// "*a***b**" is italic a and bold b, except that "**" between two words
+// inside *...* opens bold, as in "*a**b**c*".
+func closingMarker(s, marker string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case s[i] == '*' && (marker == "*" || marker == "**"):
+			run := len(s[i:]) - len(strings.TrimLeft(s[i:], "*"))
+			closes := i > 0 && s[i-1] != ' '
+			opens := i+run < len(s) && s[i+run] != ' '
+			if closes && (marker == "**" && run >= 2 || marker == "*" && (run != 2 || !opens)) {
+				return i
+			}
+			inner := "*"
+			if run >= 2 {
+				inner = "**"
+			}
+			if end := closingMarker(s[i+len(inner):], inner); opens && end >= 0 {
+				i += 2*len(inner) + end - 1
+			} else {
+				i += run - 1
+			}
+		case strings.HasPrefix(s[i:], marker):
+			return i
+		}
+	}
+	return -1
+}
+
+// flanked reports whether emphasised text starts and ends with something
+// other than a space, so that "2 * 3 * 4" stays literal.
+func flanked(inner string) bool {
+	return inner[0] != ' ' && inner[len(inner)-1] != ' '
+}
+
+// parseLink parses "[label](url)" at the start of s and returns its parts
+// and length, or a length of 0 if s does not start with a link. The URL may
+// hold balanced parentheses, as in Wikipedia links, and escaped ones.
+func parseLink(s string) (string, string, int) {
+	closeLabel := closingMarker(s[1:], "]")
+	if closeLabel < 0 {
+		return "", "", 0
+	}
+	rest := s[closeLabel+2:]
+	if !strings.HasPrefix(rest, "(") {
+		return "", "", 0
+	}
+	var url strings.Builder
+	depth := 0
+	for i := 1; i < len(rest); i++ {
+		switch {
+		case rest[i] == '\\' && i+1 < len(rest) && strings.IndexByte(`\()`, rest[i+1]) >= 0:
+			url.WriteByte(rest[i+1])
+			i++
+			continue
+		case rest[i] == '(':
+			depth++
+		case rest[i] == ')' && depth > 0:
+			depth--
+		case rest[i] == ')':
+			if strings.TrimSpace(url.String()) == "" {
+				return "", "", 0
+			}
+			return s[1 : closeLabel+1], strings.TrimSpace(url.String()), closeLabel + 2 + i + 1
+		}
+		url.WriteByte(rest[i])
+	}
+	return "", "", 0
+}
+
+func textSegment(content string, ann Annotation, link string) RichText {
+	segment := RichText{
+		Type:        "text",
+		Text:        &Text{Content: content},
+		Annotations: ann,
+		PlainText:   content,
+	}
+	if link != "" {
+		segment.Text.Link = &Link{URL: link}
+		segment.Href = &link
+	}
+	return segment
+}
+
+// mergeSegments joins neighbouring segments that have the same formatting.
+func mergeSegments(segments []RichText) []RichText {
+	var merged []RichText
+	for _, segment := range segments {
+		if n := len(merged); n > 0 && sameFormatting(merged[n-1], segment) {
+			merged[n-1].PlainText += segment.PlainText
+			merged[n-1].Text.Content += segment.Text.Content
+			continue
+		}
+		merged = append(merged, segment)
+	}
+	return merged
+}
+
+func sameFormatting(a, b RichText) bool {
+	return a.Annotations == b.Annotations && segmentURL(a) == segmentURL(b)
+}
+
+// RenderMarkdown is the inverse of ParseMarkdown: it writes rich text as
+// inline Markdown, escaping characters that would otherwise be read as
+// markup. Formatting Markdown cannot express, such as colours, underline and
+// mentions, is rendered as plain text.
+//
+// Markers are nested across segments rather than closed and reopened, so
+// that italic text with a bold word reads "*a **b** c*" and parses back the
+// same way.
+func RenderMarkdown(segments []RichText) string {
+	var out strings.Builder
+	var open []markdownMarker // outermost first
+	pending := ""             // spaces to write once markers are closed
+	for _, run := range markdownRuns(segments) {
+		// Markers must hug the text, so surrounding spaces go outside them.
+		trimmed := strings.TrimSpace(run.text)
+		if trimmed == "" {
+			pending += run.text
+			continue
+		}
+		lead := run.text[:strings.Index(run.text, trimmed)]
+		trail := run.text[len(lead)+len(trimmed):]
+
+		want := run.format.markers()
+		keep := 0
+		for keep < len(open) && hasMarker(want, open[keep]) {
+			keep++
+		}
+		for len(open) > keep {
+			out.WriteString(open[len(open)-1].close)
+			open = open[:len(open)-1]
+		}
+		out.WriteString(pending + lead)
+		for _, marker := range want {
+			if !hasMarker(open, marker) {
+				out.WriteString(marker.open)
+				open = append(open, marker)
+			}
+		}
+		if run.format.code && !strings.Contains(trimmed, "`") {
+			out.WriteString("`" + trimmed + "`")
+		} else {
+			out.WriteString(markdownEscaper.Replace(trimmed))
+		}
+		pending = trail
+	}
+	for len(open) > 0 {
+		out.WriteString(open[len(open)-1].close)
+		open = open[:len(open)-1]
+	}
+	out.WriteString(pending)
+	return out.String()
+}
+
+type markdownMarker struct{ open, close string }
+
+// markdownFormat is the formatting of rich text that Markdown can express.
+type markdownFormat struct {
+	bold, italic, strike, code bool
+	url                        string
+}
+
+type markdownRun struct {
+	text   string
+	format markdownFormat
+}
+
+// markdownRuns joins neighbouring segments that look the same in Markdown,
+// such as two italic words in different colours.
+func markdownRuns(segments []RichText) []markdownRun {
+	var runs []markdownRun
+	for _, segment := range segments {
+		a := segment.Annotations
+		format := markdownFormat{a.Bold, a.Italic, a.Strikethrough, a.Code, segmentURL(segment)}
+		if n := len(runs); n > 0 && runs[n-1].format == format {
+			runs[n-1].text += segment.PlainText
+			continue
+		}
+		runs = append(runs, markdownRun{segment.PlainText, format})
+	}
+	return runs
+}
+
+// markers lists the markers around a run, outermost first.
+func (f markdownFormat) markers() []markdownMarker {
+	var markers []markdownMarker
+	if f.url != "" {
+		markers = append(markers, markdownMarker{"[", "](" + linkDestination(f.url) + ")"})
+	}
+	if f.bold {
+		markers = append(markers, markdownMarker{"**", "**"})
+	}
+	if f.italic {
+		markers = append(markers, markdownMarker{"*", "*"})
+	}
+	if f.strike {
+		markers = append(markers, markdownMarker{"~~", "~~"})
+	}
+	return markers
+}
+
+// linkDestination writes a URL for a Markdown link. Parentheses are
+// escaped unless they are balanced, which parseLink reads as they are.
+func linkDestination(url string) string {
+	depth := 0
+	for _, c := range url {
+		switch c {
+		case '\\':
+			return linkEscaper.Replace(url)
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return linkEscaper.Replace(url)
+			}
+		}
+	}
+	if depth != 0 {
+		return linkEscaper.Replace(url)
+	}
+	return url
+}
+
+var linkEscaper = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+
+func hasMarker(markers []markdownMarker, marker markdownMarker) bool {
+	for _, m := range markers {
+		if m == marker {
+			return true
+		}
+	}
+	return false
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "~", `\~`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+// requestRichText strips the read-only fields Notion fills in from rich text
+// that is about to be sent.
+func requestRichText(segments []RichText) []RichText {
+	out := make([]RichText, len(segments))
+	for i, segment := range segments {
+		segment.PlainText = ""
+		segment.Href = nil
+		out[i] = segment
+	}
+	return out
+}
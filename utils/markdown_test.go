@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []RichText
+	}{
+		{"plain text", []RichText{textSegment("plain text", Annotation{}, "")}},
+		{"Fix **login** on `staging`", []RichText{
+			textSegment("Fix ", Annotation{}, ""),
+			textSegment("login", Annotation{Bold: true}, ""),
+			textSegment(" on ", Annotation{}, ""),
+			textSegment("staging", Annotation{Code: true}, ""),
+		}},
+		{"*a ~~b~~* [docs](https://example.com)", []RichText{
+			textSegment("a ", Annotation{Italic: true}, ""),
+			textSegment("b", Annotation{Italic: true, Strikethrough: true}, ""),
+			textSegment(" ", Annotation{}, ""),
+			textSegment("docs", Annotation{}, "https://example.com"),
+		}},
+		{"[Go](https://en.wikipedia.org/wiki/Go_(language)) and [odd](https://example.com/a\\)b)", []RichText{
+			textSegment("Go", Annotation{}, "https://en.wikipedia.org/wiki/Go_(language)"),
+			textSegment(" and ", Annotation{}, ""),
+			textSegment("odd", Annotation{}, "https://example.com/a)b"),
+		}},
+		{"**[bold link](https://example.com)**", []RichText{
+			textSegment("bold link", Annotation{Bold: true}, "https://example.com"),
+		}},
+		{"2 * 3 * 4, **open, `x` and \\*y\\*", []RichText{
+			textSegment("2 * 3 * 4, **open, ", Annotation{}, ""),
+			textSegment("x", Annotation{Code: true}, ""),
+			textSegment(" and *y*", Annotation{}, ""),
+		}},
+	}
+
+	for _, test := range tests {
+		got := ParseMarkdown(test.input)
+		if len(got) != len(test.want) {
+			t.Errorf("%q: expected %d segments, got %d: %+v", test.input, len(test.want), len(got), got)
+			continue
+		}
+		for i := range got {
+			if got[i].PlainText != test.want[i].PlainText || got[i].Text.Content != test.want[i].Text.Content || !sameFormatting(got[i], test.want[i]) {
+				t.Errorf("%q: segment %d expected %+v, got %+v", test.input, i, test.want[i], got[i])
+			}
+		}
+	}
+}
+
+func TestRenderMarkdownRoundTrips(t *testing.T) {
+	for _, input := range []string{
+		"plain text",
+		"Fix **login** on `staging`",
+		"*~~a b~~* [docs](https://example.com)",
+		"**[bold link](https://example.com)** and \\*literal\\* stars",
+	} {
+		rendered := RenderMarkdown(ParseMarkdown(input))
+		if again := ParseMarkdown(rendered); PlainText(again) != PlainText(ParseMarkdown(input)) || len(again) != len(ParseMarkdown(input)) {
+			t.Errorf("%q rendered as %q, which parses differently", input, rendered)
+		}
+	}
+
+	for _, url := range []string{"https://en.wikipedia.org/wiki/Go_(language)", "https://example.com/a)b(", `https://example.com/\(x`} {
+		link := []RichText{textSegment("link", Annotation{}, url)}
+		rendered := RenderMarkdown(link)
+		if got := ParseMarkdown(rendered); !sameRichText(got, link) {
+			t.Errorf("%q rendered as %q, which parses as %+v", url, rendered, got)
+		}
+	}
+	if got, want := RenderMarkdown([]RichText{textSegment("Go", Annotation{}, "https://en.wikipedia.org/wiki/Go_(language)")}), "[Go](https://en.wikipedia.org/wiki/Go_(language))"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	bold := textSegment(" spaced ", Annotation{Bold: true}, "")
+	if got := RenderMarkdown([]RichText{bold}); got != " **spaced** " {
+		t.Errorf("Expected the spaces outside the markers, got: %q", got)
+	}
+}
+
+func TestRenderMarkdownRoundTripsCombinedAnnotations(t *testing.T) {
+	type format struct {
+		ann  Annotation
+		link string
+	}
+	var formats []format
+	for bits := 0; bits < 48; bits++ {
+		ann := Annotation{Bold: bits&1 != 0, Italic: bits&2 != 0, Strikethrough: bits&4 != 0, Code: bits&8 != 0}
+		formats = append(formats, format{ann, []string{"", "https://example.com/a", "https://example.com/b"}[bits>>4]})
+	}
+
+	for _, x := range formats {
+		for _, y := range formats {
+			for _, z := range formats {
+				segments := mergeSegments([]RichText{
+					textSegment("a", x.ann, x.link),
+					textSegment("b c", y.ann, y.link),
+					textSegment("d", z.ann, z.link),
+				})
+				rendered := RenderMarkdown(segments)
+				if got := ParseMarkdown(rendered); !sameRichText(got, segments) {
+					t.Fatalf("%+v rendered as %q, which parses as %+v", segments, rendered, got)
+				}
+			}
+		}
+	}
+}
+
+func sameRichText(a, b []RichText) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].PlainText != b[i].PlainText || !sameFormatting(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -5,9 +5,9 @@
 package utils
 
 import (
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"context"
+	"regexp"
+	"strings"
 )
 
 type Page struct {
@@ -19,30 +19,30 @@ type Page struct {
 	Properties     map[string]interface{} `json:"properties"`
 }
 
-func getNotionPage(notionAPIKey, pageID string) (*Page, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", baseURL+"pages/"+pageID, nil)
-	if err != nil {
-		return nil, err
-	}
+var pageIDPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}`)
 
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("Notion-Version", "2022-06-28")
-	req.Header.Set("Authorization", "Bearer "+notionAPIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// ParsePageID accepts a page ID, with or without dashes, or a Notion page
+// URL such as https://www.notion.so/Title-<id>, and returns the ID. The ID
+// is taken from the last segment of the URL's path, so that a #<block-id>
+// anchor or query is ignored. Anything else is returned unchanged.
+func ParsePageID(s string) string {
+	path := s
+	if end := strings.IndexAny(path, "?#"); end >= 0 {
+		path = path[:end]
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	path = strings.TrimRight(path, "/")
+	if slash := strings.LastIndexByte(path, '/'); slash >= 0 {
+		path = path[slash+1:]
 	}
+	if matches := pageIDPattern.FindAllString(path, -1); len(matches) > 0 {
+		return matches[len(matches)-1]
+	}
+	return s
+}
 
+func (c *NotionClient) getNotionPage(ctx context.Context, pageID string) (*Page, error) {
 	var page Page
-	err = json.Unmarshal(body, &page)
+	err := c.do(ctx, "GET", "/pages/"+pageID, nil, &page)
 	if err != nil {
 		return nil, err
 	}
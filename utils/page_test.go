@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestParsePageID(t *testing.T) {
+	const pageID = "0123456789abcdef0123456789abcdef"
+	const blockID = "fedcba98-7654-3210-fedc-ba9876543210"
+	tests := map[string]string{
+		pageID:                                                                    pageID,
+		"01234567-89ab-cdef-0123-456789abcdef":                                    "01234567-89ab-cdef-0123-456789abcdef",
+		"https://www.notion.so/Tasks-" + pageID:                                   pageID,
+		"https://www.notion.so/team/Tasks-" + pageID + "/":                        pageID,
+		"https://www.notion.so/Tasks-" + pageID + "#" + blockID:                   pageID,
+		"https://www.notion.so/Tasks-" + pageID + "?pvs=4#" + blockID:             pageID,
+		"https://www.notion.so/" + blockID + "/Tasks-" + pageID + "?v=" + blockID: pageID,
+		"not an ID": "not an ID",
+	}
+	for input, want := range tests {
+		if got := ParsePageID(input); got != want {
+			t.Errorf("%q: expected %q, got %q", input, want, got)
+		}
+	}
+}
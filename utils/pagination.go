@@ -0,0 +1,106 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// MaxPageSize is the largest page_size the Notion API accepts.
+const MaxPageSize = 100
+
+// BlockIterator walks the children of a block, fetching one API page at a
+// time and following next_cursor until Notion reports has_more is false.
+//
+//	it := client.BlockChildren(ctx, pageID, 0)
+//	for it.Next() {
+//		block := it.Block()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type BlockIterator struct {
+	ctx      context.Context
+	client   *NotionClient
+	blockID  string
+	pageSize int
+
+	buffer  []Block
+	cursor  string
+	started bool
+	done    bool
+	current Block
+	err     error
+}
+
+// BlockChildren returns an iterator over the children of blockID. A pageSize
+// of zero leaves the page size up to the API.
+func (c *NotionClient) BlockChildren(ctx context.Context, blockID string, pageSize int) *BlockIterator {
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return &BlockIterator{ctx: ctx, client: c, blockID: blockID, pageSize: pageSize}
+}
+
+// Next advances to the next block, fetching another page when needed. It
+// returns false once the children are exhausted or an error occurred.
+func (it *BlockIterator) Next() bool {
+	for len(it.buffer) == 0 {
+		if it.err != nil || it.done {
+			return false
+		}
+		it.fetch()
+	}
+	it.current = it.buffer[0]
+	it.buffer = it.buffer[1:]
+	return true
+}
+
+// Block returns the block the iterator is positioned on.
+func (it *BlockIterator) Block() Block {
+	return it.current
+}
+
+// Err returns the first error encountered while fetching pages.
+func (it *BlockIterator) Err() error {
+	return it.err
+}
+
+func (it *BlockIterator) fetch() {
+	query := url.Values{}
+	if it.pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(it.pageSize))
+	}
+	if it.started {
+		query.Set("start_cursor", it.cursor)
+	}
+	path := "/blocks/" + it.blockID + "/children"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var blockList BlockList
+	if err := it.client.do(it.ctx, "GET", path, nil, &blockList); err != nil {
+		it.err = err
+		return
+	}
+	it.started = true
+	it.buffer = blockList.Results
+	it.cursor = blockList.NextCursor
+	it.done = !blockList.HasMore || blockList.NextCursor == ""
+}
+
+// GetAllBlockChildren collects every child of blockID across all pages.
+func (c *NotionClient) GetAllBlockChildren(ctx context.Context, blockID string) ([]Block, error) {
+	var children []Block
+	it := c.BlockChildren(ctx, blockID, MaxPageSize)
+	for it.Next() {
+		children = append(children, it.Block())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return children, nil
+}
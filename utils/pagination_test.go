@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// newPagedServer serves total to-do blocks as children of any block, honouring
+// page_size and start_cursor the same way the Notion API does.
+func newPagedServer(t *testing.T, total int, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		pageSize := 100
+		if v := r.URL.Query().Get("page_size"); v != "" {
+			pageSize, _ = strconv.Atoi(v)
+		}
+		start := 0
+		if v := r.URL.Query().Get("start_cursor"); v != "" {
+			start, _ = strconv.Atoi(v)
+		}
+		end := start + pageSize
+		if end > total {
+			end = total
+		}
+
+		result := BlockList{Object: "list"}
+		for i := start; i < end; i++ {
+			block := mockBlock([]string{fmt.Sprintf("task %d", i+1)})
+			block.ID = fmt.Sprintf("block%d", i+1)
+			result.Results = append(result.Results, block)
+		}
+		if end < total {
+			result.HasMore = true
+			result.NextCursor = strconv.Itoa(end)
+		}
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			t.Errorf("Error encoding response: %v", err)
+		}
+	}))
+}
+
+func TestBlockChildrenFollowsCursor(t *testing.T) {
+	requests := 0
+	server := newPagedServer(t, 25, &requests)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL))
+	it := client.BlockChildren(context.Background(), "pageID", 10)
+	count := 0
+	for it.Next() {
+		count++
+		if want := fmt.Sprintf("block%d", count); it.Block().ID != want {
+			t.Errorf("Expected block %s, got: %s", want, it.Block().ID)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if count != 25 {
+		t.Errorf("Expected 25 blocks, got: %d", count)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got: %d", requests)
+	}
+}
+
+func TestGetBlocksReadsEveryPage(t *testing.T) {
+	requests := 0
+	server := newPagedServer(t, 250, &requests)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL))
+	blocks, err := client.GetBlocks(context.Background(), "pageID")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(blocks) != 250 {
+		t.Errorf("Expected 250 blocks, got: %d", len(blocks))
+	}
+}
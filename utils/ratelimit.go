@@ -0,0 +1,65 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Notion allows an average of three requests per second per integration,
+// with short bursts above that.
+const (
+	DefaultRateLimit = 3
+	DefaultBurst     = 3
+)
+
+// rateLimiter spaces requests out to an average rate while letting a small
+// burst through immediately. It is shared by every goroutine using a client.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond), burst: burst}
+}
+
+// WithRateLimit caps the client at perSecond requests on average, allowing
+// bursts of up to burst requests. A rate of zero disables limiting.
+func WithRateLimit(perSecond float64, burst int) ClientOption {
+	return func(c *NotionClient) {
+		c.limiter = newRateLimiter(perSecond, burst)
+	}
+}
+
+// Wait blocks until the caller may send a request or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, delay)
+}
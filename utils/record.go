@@ -0,0 +1,196 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TaskRecord is the machine-readable form of a task that `list` and `show`
+// print with --output. Its field names and meanings are a stable schema:
+// fields may be added, but existing ones are not renamed or changed.
+type TaskRecord struct {
+	// Position is the task's number as `list` shows it and check, uncheck
+	// and delete accept it, e.g. "3" or "3.1" for a subtask.
+	Position string `json:"position"`
+	// Depth is 0 for top-level tasks, 1 for their subtasks and so on.
+	Depth int    `json:"depth"`
+	ID    string `json:"id"`
+	// Text is the plain text; RichText the Notion rich text objects.
+	Text     string     `json:"text"`
+	RichText []RichText `json:"rich_text"`
+	Checked  bool       `json:"checked"`
+	// Color is the colour of the to-do block, "default" if it has none.
+	Color string `json:"color"`
+	// CreatedTime and LastEditedTime are ISO 8601 timestamps in UTC.
+	CreatedTime    string `json:"created_time"`
+	LastEditedTime string `json:"last_edited_time"`
+	// URL opens the page in Notion scrolled to the task.
+	URL string `json:"url"`
+	// Parent is the ID of the task this one is a subtask of, or null.
+	Parent *string `json:"parent"`
+}
+
+// NewTaskRecord builds the record for a task on the page pageID.
+func NewTaskRecord(pageID string, task *Task) TaskRecord {
+	block := task.Block
+	record := TaskRecord{
+		Position:       task.Number,
+		Depth:          task.Depth(),
+		ID:             block.ID,
+		Text:           block.ToDo.PlainText(),
+		RichText:       block.ToDo.RichText,
+		Checked:        block.ToDo.Checked,
+		Color:          block.ToDo.Color,
+		CreatedTime:    block.CreatedTime,
+		LastEditedTime: block.LastEditedTime,
+		URL:            BlockURL(pageID, block.ID),
+	}
+	if record.Color == "" {
+		record.Color = "default"
+	}
+	if record.RichText == nil {
+		record.RichText = []RichText{}
+	}
+	if task.Parent != nil {
+		parent := task.Parent.Block.ID
+		record.Parent = &parent
+	}
+	return record
+}
+
+// BlockURL links to a block within its page.
+func BlockURL(pageID, blockID string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(pageID, "-", "") + "#" + strings.ReplaceAll(blockID, "-", "")
+}
+
+// OutputFormats are the formats WriteRecords understands, besides the
+// human-readable text each command prints by default.
+var OutputFormats = []string{"json", "ndjson", "csv", "tsv", "yaml", "markdown"}
+
+// recordColumns are the CSV and TSV columns. rich_text is written as inline
+// Markdown there, and a missing parent as an empty field.
+var recordColumns = []string{"position", "depth", "id", "text", "rich_text", "checked", "color", "created_time", "last_edited_time", "url", "parent"}
+
+// WriteRecords writes task records to w in one of OutputFormats.
+func WriteRecords(w io.Writer, format string, records []TaskRecord) error {
+	switch format {
+	case "json":
+		if records == nil {
+			records = []TaskRecord{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "ndjson":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeDelimited(csv.NewWriter(w), records)
+	case "tsv":
+		writer := csv.NewWriter(w)
+		writer.Comma = '\t'
+		return writeDelimited(writer, records)
+	case "yaml":
+		return writeYAML(w, records)
+	case "markdown":
+		return writeChecklist(w, records)
+	}
+	return fmt.Errorf("unknown output format %q; use text or one of %s", format, strings.Join(OutputFormats, ", "))
+}
+
+func (r TaskRecord) columns() []string {
+	parent := ""
+	if r.Parent != nil {
+		parent = *r.Parent
+	}
+	return []string{
+		r.Position, strconv.Itoa(r.Depth), r.ID, r.Text, RenderMarkdown(r.RichText), strconv.FormatBool(r.Checked),
+		r.Color, r.CreatedTime, r.LastEditedTime, r.URL, parent,
+	}
+}
+
+func writeDelimited(writer *csv.Writer, records []TaskRecord) error {
+	writer.Write(recordColumns)
+	for _, record := range records {
+		writer.Write(record.columns())
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeYAML writes records as a YAML sequence. Strings are double quoted,
+// and rich text, being JSON, is written in YAML's flow style.
+func writeYAML(w io.Writer, records []TaskRecord) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	var out strings.Builder
+	for _, r := range records {
+		richText, err := json.Marshal(r.RichText)
+		if err != nil {
+			return err
+		}
+		parent := "null"
+		if r.Parent != nil {
+			parent = yamlString(*r.Parent)
+		}
+		fmt.Fprintf(&out, "- position: %s\n", yamlString(r.Position))
+		fmt.Fprintf(&out, "  depth: %d\n", r.Depth)
+		fmt.Fprintf(&out, "  id: %s\n", yamlString(r.ID))
+		fmt.Fprintf(&out, "  text: %s\n", yamlString(r.Text))
+		fmt.Fprintf(&out, "  rich_text: %s\n", richText)
+		fmt.Fprintf(&out, "  checked: %t\n", r.Checked)
+		fmt.Fprintf(&out, "  color: %s\n", yamlString(r.Color))
+		fmt.Fprintf(&out, "  created_time: %s\n", yamlString(r.CreatedTime))
+		fmt.Fprintf(&out, "  last_edited_time: %s\n", yamlString(r.LastEditedTime))
+		fmt.Fprintf(&out, "  url: %s\n", yamlString(r.URL))
+		fmt.Fprintf(&out, "  parent: %s\n", parent)
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, whose escapes are a
+// superset of JSON's.
+func yamlString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// writeChecklist writes records as a GitHub-style Markdown task list, with
+// subtasks indented under their parents. A subtask listed without its parent
+// is indented no deeper than the item before it allows.
+func writeChecklist(w io.Writer, records []TaskRecord) error {
+	var out strings.Builder
+	previous := -1
+	for _, r := range records {
+		box := " "
+		if r.Checked {
+			box = "x"
+		}
+		depth := r.Depth
+		if depth > previous+1 {
+			depth = previous + 1
+		}
+		previous = depth
+		fmt.Fprintf(&out, "%s- [%s] %s\n", strings.Repeat("  ", depth), box, RenderMarkdown(r.RichText))
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
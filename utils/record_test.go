@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testRecords() []TaskRecord {
+	parent := &Task{Number: "1", Block: Block{
+		ID:             "11111111-2222-3333-4444-555555555555",
+		CreatedTime:    "2023-05-01T08:00:00.000Z",
+		LastEditedTime: "2023-05-02T09:30:00.000Z",
+		ToDo:           &ToDo{Checked: true, RichText: ParseMarkdown("write **report**, \"final\"")},
+	}}
+	child := &Task{Number: "1.1", Parent: parent, Block: Block{
+		ID:   "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee",
+		ToDo: &ToDo{Color: "red", RichText: PlainRichText("outline\tdraft")},
+	}}
+	pageID := "99999999-8888-7777-6666-555555555555"
+	return []TaskRecord{NewTaskRecord(pageID, parent), NewTaskRecord(pageID, child)}
+}
+
+func TestNewTaskRecord(t *testing.T) {
+	records := testRecords()
+	if records[0].Text != `write report, "final"` || records[0].Color != "default" || records[0].Parent != nil {
+		t.Errorf("Unexpected record: %+v", records[0])
+	}
+	if records[0].URL != "https://www.notion.so/99999999888877776666555555555555#11111111222233334444555555555555" {
+		t.Errorf("Unexpected URL: %s", records[0].URL)
+	}
+	if records[1].Depth != 1 || records[1].Parent == nil || *records[1].Parent != records[0].ID {
+		t.Errorf("Expected the subtask to point at its parent, got: %+v", records[1])
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	records := testRecords()
+	write := func(format string) string {
+		var out bytes.Buffer
+		if err := WriteRecords(&out, format, records); err != nil {
+			t.Fatalf("Got error writing %s: %v", format, err)
+		}
+		return out.String()
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal([]byte(write("json")), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if len(decoded) != 2 || decoded[0]["parent"] != nil || decoded[1]["parent"] != records[0].ID || decoded[0]["checked"] != true {
+		t.Errorf("Unexpected JSON: %v", decoded)
+	}
+	for _, field := range []string{"position", "depth", "id", "text", "rich_text", "checked", "color", "created_time", "last_edited_time", "url", "parent"} {
+		if _, ok := decoded[0][field]; !ok {
+			t.Errorf("Expected field %s in the JSON record", field)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(write("ndjson")), "\n")
+	if len(lines) != 2 || !json.Valid([]byte(lines[1])) {
+		t.Errorf("Expected one JSON object per line, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	csvOut := write("csv")
+	if !strings.HasPrefix(csvOut, "position,depth,id,text,rich_text,checked,") ||
+		!strings.Contains(csvOut, `1,0,11111111-2222-3333-4444-555555555555,"write report, ""final""","write **report**, ""final""",true,default,`) {
+		t.Errorf("Unexpected CSV:\n%s", csvOut)
+	}
+	if tsv := write("tsv"); !strings.Contains(tsv, "\t\"outline\tdraft\"\t") {
+		t.Errorf("Expected a quoted field for text with a tab, got:\n%s", tsv)
+	}
+
+	yaml := write("yaml")
+	for _, want := range []string{"- position: \"1\"\n  depth: 0\n", `  text: "write report, \"final\""`, "  parent: null\n", "  color: \"red\"\n"} {
+		if !strings.Contains(yaml, want) {
+			t.Errorf("Expected %q in the YAML, got:\n%s", want, yaml)
+		}
+	}
+
+	if got, want := write("markdown"), "- [x] write **report**, \"final\"\n  - [ ] outline\tdraft\n"; got != want {
+		t.Errorf("Expected Markdown %q, got %q", want, got)
+	}
+
+	if err := WriteRecords(&bytes.Buffer{}, "xml", records); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,103 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// notionColors maps Notion's named colours to ANSI attributes. Colours the
+// basic palette lacks use the 256-colour palette.
+var notionColors = map[string][]color.Attribute{
+	"gray":   {color.FgHiBlack},
+	"brown":  {38, 5, 130},
+	"orange": {38, 5, 208},
+	"yellow": {color.FgYellow},
+	"green":  {color.FgGreen},
+	"blue":   {color.FgBlue},
+	"purple": {color.FgMagenta},
+	"pink":   {38, 5, 205},
+	"red":    {color.FgRed},
+
+	"gray_background":   {color.BgHiBlack},
+	"brown_background":  {48, 5, 130},
+	"orange_background": {48, 5, 208},
+	"yellow_background": {color.BgYellow},
+	"green_background":  {color.BgGreen},
+	"blue_background":   {color.BgBlue},
+	"purple_background": {color.BgMagenta},
+	"pink_background":   {48, 5, 205},
+	"red_background":    {color.BgRed},
+}
+
+// inlineCode is how code segments are shown: on a dark grey background.
+var inlineCode = []color.Attribute{48, 5, 236}
+
+// RenderRichText renders rich text segments for a terminal, with their
+// annotations, colours and links. base styles segments that have Notion's
+// default colour. When colour output is off (color.NoColor, set when stdout
+// is not a terminal or NO_COLOR is set) it returns the plain text.
+func RenderRichText(segments []RichText, base ...color.Attribute) string {
+	if color.NoColor {
+		return PlainText(segments)
+	}
+	var out strings.Builder
+	for _, segment := range segments {
+		out.WriteString(renderSegment(segment, base))
+	}
+	return out.String()
+}
+
+func renderSegment(segment RichText, base []color.Attribute) string {
+	var attrs []color.Attribute
+	a := segment.Annotations
+	if a.Bold {
+		attrs = append(attrs, color.Bold)
+	}
+	if a.Italic {
+		attrs = append(attrs, color.Italic)
+	}
+	if a.Underline {
+		attrs = append(attrs, color.Underline)
+	}
+	if a.Strikethrough {
+		attrs = append(attrs, color.CrossedOut)
+	}
+	if a.Code {
+		attrs = append(attrs, inlineCode...)
+	}
+	if colour, ok := notionColors[a.Color]; ok {
+		attrs = append(attrs, colour...)
+	} else {
+		attrs = append(attrs, base...)
+	}
+
+	text := segment.PlainText
+	if len(attrs) > 0 {
+		text = color.New(attrs...).Sprint(text)
+	}
+	if url := segmentURL(segment); url != "" {
+		text = hyperlink(url, text)
+	}
+	return text
+}
+
+func segmentURL(segment RichText) string {
+	if segment.Href != nil {
+		return *segment.Href
+	}
+	if segment.Text != nil && segment.Text.Link != nil {
+		return segment.Text.Link.URL
+	}
+	return ""
+}
+
+// hyperlink wraps text in an OSC 8 escape so terminals that support it make
+// it clickable; others show just the text.
+func hyperlink(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
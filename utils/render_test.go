@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func segments() []RichText {
+	url := "https://example.com/login"
+	return []RichText{
+		{PlainText: "Fix "},
+		{PlainText: "login", Annotations: Annotation{Bold: true}, Href: &url},
+		{PlainText: " on "},
+		{PlainText: "staging", Annotations: Annotation{Code: true, Color: "red"}},
+	}
+}
+
+func TestRenderRichTextPlain(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = true
+
+	if got := RenderRichText(segments()); got != "Fix login on staging" {
+		t.Errorf("Expected every segment as plain text, got: %q", got)
+	}
+}
+
+func TestRenderRichTextANSI(t *testing.T) {
+	defer func(noColor bool) { color.NoColor = noColor }(color.NoColor)
+	color.NoColor = false
+
+	got := RenderRichText(segments())
+	for _, want := range []string{
+		"Fix ",
+		"\x1b]8;;https://example.com/login\x1b\\\x1b[1mlogin\x1b[0m\x1b]8;;\x1b\\",
+		"\x1b[48;5;236;31mstaging\x1b[0m",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in %q", want, got)
+		}
+	}
+	if base := RenderRichText([]RichText{{PlainText: "plain"}}, color.FgHiWhite); base != "\x1b[97mplain\x1b[0m" {
+		t.Errorf("Expected the base colour for default segments, got: %q", base)
+	}
+}
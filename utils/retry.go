@@ -0,0 +1,135 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how the client retries rate limited and transient
+// failures. MaxRetries is the retry budget for a single API call; zero turns
+// retrying off.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy for this client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *NotionClient) {
+		c.retry = policy
+	}
+}
+
+// backoff returns a jittered exponential delay for the given zero-based
+// attempt: a random duration between half and all of BaseDelay*2^attempt,
+// capped at MaxDelay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryAfter parses the Retry-After header, which Notion sends in seconds on
+// rate_limited responses.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		delay := time.Until(when)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// isIdempotent reports whether repeating a request cannot change its outcome.
+// Appending children creates new blocks on every call, so it is the one write
+// this package must not blindly resend. A resent DELETE can fail where the
+// first one was applied; do treats that failure as success, see
+// alreadyArchived.
+func isIdempotent(method, path string) bool {
+	path = strings.SplitN(path, "?", 2)[0]
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return true
+	case http.MethodPatch:
+		return !strings.HasSuffix(path, "/children")
+	case http.MethodPost:
+		return path == "/search" || strings.HasSuffix(path, "/query")
+	}
+	return false
+}
+
+// alreadyArchived reports whether the error to a resent DELETE says that the
+// block is gone, which is what an earlier attempt that was applied but
+// failed on its way back leaves behind.
+func alreadyArchived(err *APIError) bool {
+	return err.Code == CodeObjectNotFound ||
+		err.Code == CodeValidationError && strings.Contains(strings.ToLower(err.Message), "archived")
+}
+
+// shouldRetryStatus decides whether a response status is worth another try.
+// A 429 means Notion rejected the request before doing any work, so it is
+// safe to resend even non-idempotent calls. Gateway errors may have been
+// applied upstream, so they are only retried for idempotent calls.
+func shouldRetryStatus(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// shouldRetryError decides whether a transport error is worth another try.
+// Failing to dial means the request never left this machine, which makes it
+// safe for any call; other network errors are only retried when idempotent.
+func shouldRetryError(err error, idempotent bool) bool {
+	if isDialError(err) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return idempotent
+	}
+	return false
+}
+
+// isDialError reports whether a request failed before it was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var fastRetries = RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// newFlakyServer fails the first failures requests with status before
+// answering normally.
+func newFlakyServer(status, failures int, attempts *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*attempts++
+		if *attempts <= failures {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"object":"error"}`))
+			return
+		}
+		w.Write([]byte(`{"object":"list","results":[]}`))
+	}))
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	attempts := 0
+	server := newFlakyServer(http.StatusTooManyRequests, 2, &attempts)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(fastRetries))
+	if err := client.AddNewToDoItem(context.Background(), "pageID", "new todo"); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", attempts)
+	}
+}
+
+func TestRetriesServerErrorsForIdempotentRequests(t *testing.T) {
+	attempts := 0
+	server := newFlakyServer(http.StatusServiceUnavailable, 2, &attempts)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(fastRetries))
+	if _, err := client.GetBlocks(context.Background(), "pageID"); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got: %d", attempts)
+	}
+}
+
+func TestDoesNotRetryServerErrorsWhenAppending(t *testing.T) {
+	attempts := 0
+	server := newFlakyServer(http.StatusBadGateway, 1, &attempts)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(fastRetries))
+	if err := client.AddNewToDoItem(context.Background(), "pageID", "new todo"); err == nil {
+		t.Errorf("Expected an error for a 502 response")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got: %d", attempts)
+	}
+}
+
+func TestRetryBudgetIsRespected(t *testing.T) {
+	attempts := 0
+	server := newFlakyServer(http.StatusTooManyRequests, 100, &attempts)
+	defer server.Close()
+
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(fastRetries))
+	if _, err := client.GetBlocks(context.Background(), "pageID"); err == nil {
+		t.Errorf("Expected an error once the retry budget is spent")
+	}
+	if attempts != fastRetries.MaxRetries+1 {
+		t.Errorf("Expected %d attempts, got: %d", fastRetries.MaxRetries+1, attempts)
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	attempts := 0
+	server := newFlakyServer(http.StatusServiceUnavailable, 100, &attempts)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	slowRetries := RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: time.Second}
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(slowRetries))
+
+	start := time.Now()
+	_, err := client.GetBlocks(ctx, "pageID")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected retrying to stop with the context, took %v", elapsed)
+	}
+}
+
+func TestResentDeleteOfArchivedBlockSucceeds(t *testing.T) {
+	for _, test := range []struct {
+		first   int // status of the first attempt, 0 for none
+		wantErr bool
+	}{
+		{http.StatusBadGateway, false},
+		{http.StatusTooManyRequests, true},
+		{0, true},
+	} {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 && test.first != 0 {
+				// The first attempt archives the block but fails on the way back.
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(test.first)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"Could not find block."}`))
+		}))
+
+		client := NewNotionClient("fakeKey", WithBaseURL(server.URL), WithRetryPolicy(fastRetries))
+		err := client.do(context.Background(), http.MethodDelete, "/blocks/blockID", nil, nil)
+		if (err != nil) != test.wantErr {
+			t.Errorf("First attempt %d: expected an error %v, got: %v", test.first, test.wantErr, err)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,59 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Section is a heading on the page together with the tasks between it and
+// the next heading.
+type Section struct {
+	Title   string
+	Heading Block
+	Tasks   []*Task
+
+	parentID string // the block new tasks of the section are added to
+	lastID   string // the section's last task among parentID's children
+}
+
+func (s *Section) add(task *Task, parentID string) {
+	s.Tasks = append(s.Tasks, task)
+	if parentID == s.parentID {
+		s.lastID = task.Block.ID
+	}
+}
+
+// InsertionPoint returns where a task added to the section goes: the block
+// to append it to and the child to append it after, right behind the
+// section's last task. An empty after appends at the end, which is where a
+// task goes in a toggleable heading with no tasks yet.
+func (s *Section) InsertionPoint() (parentID, after string) {
+	if s.lastID != "" {
+		return s.parentID, s.lastID
+	}
+	if s.parentID == s.Heading.ID {
+		return s.parentID, ""
+	}
+	return s.parentID, s.Heading.ID
+}
+
+// Section finds a section by its title, ignoring case and surrounding space.
+// When several headings share the title, the first one wins.
+func (l *TaskList) Section(title string) (*Section, error) {
+	title = strings.TrimSpace(title)
+	var titles []string
+	for _, section := range l.Sections {
+		if strings.EqualFold(strings.TrimSpace(section.Title), title) {
+			return section, nil
+		}
+		titles = append(titles, fmt.Sprintf("%q", section.Title))
+	}
+	if len(titles) == 0 {
+		return nil, fmt.Errorf("there is no section %q, the page has no headings", title)
+	}
+	return nil, fmt.Errorf("there is no section %q, the sections are %s", title, strings.Join(titles, ", "))
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+)
+
+func titledHeading(id, title string) Block {
+	block := headingBlock(id)
+	block.Heading2 = &Heading{RichText: []RichText{{PlainText: title}}}
+	return block
+}
+
+func TestSections(t *testing.T) {
+	list := NewTaskList("pageID", []Block{
+		todoBlock("loose", "before any heading"),
+		titledHeading("today", "Today"),
+		todoBlock("t1", "first today"),
+		todoBlock("t2", "second today"),
+		{Object: "block", ID: "notes", Type: TypeParagraph},
+		titledHeading("backlog", "Backlog"),
+	})
+
+	if list.Tasks[0].Section != nil {
+		t.Errorf("Expected no section before the first heading, got: %v", list.Tasks[0].Section.Title)
+	}
+	today, err := list.Section(" today ")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(today.Tasks) != 2 || list.Tasks[2].Section != today {
+		t.Errorf("Expected 2 tasks in Today, got: %d", len(today.Tasks))
+	}
+	if parent, after := today.InsertionPoint(); parent != "pageID" || after != "t2" {
+		t.Errorf("Expected to add after the last task of Today, got: %s after %s", parent, after)
+	}
+
+	backlog, _ := list.Section("Backlog")
+	if parent, after := backlog.InsertionPoint(); parent != "pageID" || after != "backlog" {
+		t.Errorf("Expected to add right after an empty section's heading, got: %s after %s", parent, after)
+	}
+	if _, err := list.Section("Someday"); err == nil {
+		t.Errorf("Expected an error for a missing section")
+	}
+}
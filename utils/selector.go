@@ -0,0 +1,213 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Selector describes a set of tasks for bulk commands. Positions, Numbers
+// and IDs pick tasks by top-level number, subtask number such as "3.2" or
+// block ID prefix; All, Done, Open and Match pick
+// every task that passes them. When both are given, only the listed tasks
+// that pass the filters are selected.
+type Selector struct {
+	Positions []int
+	Numbers   []string
+	IDs       []string
+	All       bool
+	Done      bool
+	Open      bool
+	Match     *regexp.Regexp
+}
+
+// ParsePositions parses a selector expression such as "1,3,5-8" into the
+// positions it names, in order and without duplicates.
+func ParsePositions(expr string) ([]int, error) {
+	sel, err := ParseSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(sel.IDs) > 0 {
+		return nil, fmt.Errorf("could not convert %q to a task number", sel.IDs[0])
+	}
+	if len(sel.Numbers) > 0 {
+		return nil, fmt.Errorf("%q is a subtask, not a task number", sel.Numbers[0])
+	}
+	return sel.Positions, nil
+}
+
+// ParseSelector parses a selector expression that may mix positions, ranges,
+// subtask numbers and ID prefixes, such as "1,3,5-8,4.1-4.3,a1b2".
+func ParseSelector(expr string) (Selector, error) {
+	var sel Selector
+	seen := map[int]bool{}
+	seenNumbers := map[string]bool{}
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if numberRangePattern.MatchString(part) {
+			numbers, err := parseNumberRange(part)
+			if err != nil {
+				return sel, err
+			}
+			for _, number := range numbers {
+				if !seenNumbers[number] {
+					seenNumbers[number] = true
+					sel.Numbers = append(sel.Numbers, number)
+				}
+			}
+			continue
+		}
+		if !rangePattern.MatchString(part) {
+			if err := checkIDPrefix(part); err != nil {
+				return sel, err
+			}
+			sel.IDs = append(sel.IDs, part)
+			continue
+		}
+		first, last, err := parseRange(part)
+		if err != nil {
+			return sel, err
+		}
+		for position := first; position <= last; position++ {
+			if !seen[position] {
+				seen[position] = true
+				sel.Positions = append(sel.Positions, position)
+			}
+		}
+	}
+	if len(sel.Positions) == 0 && len(sel.Numbers) == 0 && len(sel.IDs) == 0 {
+		return sel, fmt.Errorf("%q does not name any tasks", expr)
+	}
+	return sel, nil
+}
+
+var (
+	rangePattern       = regexp.MustCompile(`^\d+(\s*-\s*\d+)?$`)
+	numberPattern      = regexp.MustCompile(`^\d+(\.\d+)+$`)
+	numberRangePattern = regexp.MustCompile(`^\d+(\.\d+)+(\s*-\s*\d+(\.\d+)+)?$`)
+)
+
+func parseRange(part string) (int, int, error) {
+	bounds := strings.SplitN(part, "-", 2)
+	first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not convert %q to a task number", part)
+	}
+	last := first
+	if len(bounds) == 2 {
+		last, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("could not convert %q to a range of task numbers", part)
+		}
+	}
+	if first < 1 || last < first {
+		return 0, 0, fmt.Errorf("%q is not a valid range of task numbers", part)
+	}
+	return first, last, nil
+}
+
+// parseNumberRange expands a subtask number or a range of siblings such as
+// "4.1-4.3".
+func parseNumberRange(part string) ([]string, error) {
+	bounds := strings.SplitN(part, "-", 2)
+	first := strings.TrimSpace(bounds[0])
+	if len(bounds) == 1 {
+		return []string{first}, nil
+	}
+	last := strings.TrimSpace(bounds[1])
+	firstDot, lastDot := strings.LastIndex(first, "."), strings.LastIndex(last, ".")
+	if first[:firstDot] != last[:lastDot] {
+		return nil, fmt.Errorf("%q is not a range of subtasks of one task", part)
+	}
+	from, err := strconv.Atoi(first[firstDot+1:])
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %q to a range of task numbers", part)
+	}
+	to, err := strconv.Atoi(last[lastDot+1:])
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %q to a range of task numbers", part)
+	}
+	if from < 1 || to < from {
+		return nil, fmt.Errorf("%q is not a valid range of task numbers", part)
+	}
+	var numbers []string
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, first[:firstDot+1]+strconv.Itoa(n))
+	}
+	return numbers, nil
+}
+
+// IsEmpty reports whether the selector names no tasks at all.
+func (s Selector) IsEmpty() bool {
+	return len(s.Positions) == 0 && len(s.Numbers) == 0 && len(s.IDs) == 0 && !s.All && !s.Done && !s.Open && s.Match == nil
+}
+
+func (s Selector) matches(task *Task) bool {
+	if s.Done && !task.Block.ToDo.Checked {
+		return false
+	}
+	if s.Open && task.Block.ToDo.Checked {
+		return false
+	}
+	if s.Match != nil && !s.Match.MatchString(task.Block.ToDo.PlainText()) {
+		return false
+	}
+	return true
+}
+
+// Select resolves a selector against the list. Unknown positions and IDs are
+// an error, so a typo never silently turns into "nothing happened".
+func (l *TaskList) Select(sel Selector) ([]*Task, error) {
+	if sel.IsEmpty() {
+		return nil, fmt.Errorf("no tasks selected")
+	}
+	candidates := l.Tasks
+	if len(sel.Positions) > 0 || len(sel.Numbers) > 0 || len(sel.IDs) > 0 {
+		candidates = nil
+		seen := map[*Task]bool{}
+		add := func(task *Task) {
+			if !seen[task] {
+				seen[task] = true
+				candidates = append(candidates, task)
+			}
+		}
+		for _, position := range sel.Positions {
+			task, err := l.Resolve(position)
+			if err != nil {
+				return nil, err
+			}
+			add(task)
+		}
+		for _, number := range sel.Numbers {
+			task, err := l.ResolveNumber(number)
+			if err != nil {
+				return nil, err
+			}
+			add(task)
+		}
+		for _, id := range sel.IDs {
+			task, err := l.ResolveID(id)
+			if err != nil {
+				return nil, err
+			}
+			add(task)
+		}
+	}
+
+	var selected []*Task
+	for _, task := range candidates {
+		if sel.matches(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected, nil
+}
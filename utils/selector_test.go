@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParsePositions(t *testing.T) {
+	positions, err := ParsePositions("1,3, 5-8,3")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if want := []int{1, 3, 5, 6, 7, 8}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("Expected %v, got: %v", want, positions)
+	}
+
+	for _, expr := range []string{"", "0", "a", "5-2", "1-x"} {
+		if _, err := ParsePositions(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	done := todoBlock("done", "ship the release")
+	done.ToDo.Checked = true
+	list := NewTaskList("pageID", []Block{
+		todoBlock("open", "write release notes"),
+		done,
+		todoBlock("other", "water the plants"),
+	})
+
+	ids := func(tasks []*Task) []string {
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.Block.ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name string
+		sel  Selector
+		want []string
+	}{
+		{"positions", Selector{Positions: []int{3, 1}}, []string{"other", "open"}},
+		{"all", Selector{All: true}, []string{"open", "done", "other"}},
+		{"done", Selector{Done: true}, []string{"done"}},
+		{"open", Selector{Open: true}, []string{"open", "other"}},
+		{"match", Selector{Match: regexp.MustCompile("release")}, []string{"open", "done"}},
+		{"positions and filter", Selector{Positions: []int{1, 2}, Open: true}, []string{"open"}},
+	}
+	for _, tc := range cases {
+		tasks, err := list.Select(tc.sel)
+		if err != nil {
+			t.Errorf("%s: got error: %v", tc.name, err)
+			continue
+		}
+		if got := ids(tasks); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got: %v", tc.name, tc.want, got)
+		}
+	}
+
+	if _, err := list.Select(Selector{Positions: []int{4}}); err == nil {
+		t.Errorf("Expected an error for an unknown position")
+	}
+	if _, err := list.Select(Selector{}); err == nil {
+		t.Errorf("Expected an error for an empty selector")
+	}
+}
+
+func TestParseSelectorSubtasks(t *testing.T) {
+	sel, err := ParseSelector("2, 3.1, 4.2-4.4, 3.1, a1b2")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if want := []string{"3.1", "4.2", "4.3", "4.4"}; !reflect.DeepEqual(sel.Numbers, want) {
+		t.Errorf("Expected %v, got: %v", want, sel.Numbers)
+	}
+	if !reflect.DeepEqual(sel.Positions, []int{2}) || !reflect.DeepEqual(sel.IDs, []string{"a1b2"}) {
+		t.Errorf("Expected position 2 and ID a1b2, got: %v %v", sel.Positions, sel.IDs)
+	}
+
+	for _, expr := range []string{"3.1-4.2", "3.4-3.2", "3.0-3.1"} {
+		if _, err := ParseSelector(expr); err == nil {
+			t.Errorf("Expected an error for %q", expr)
+		}
+	}
+}
+
+func TestParseSelectorRejectsShortOrStrayIDs(t *testing.T) {
+	for _, expr := range []string{"3-", "1-", "-3", "#3", "a", "abc", "1,ab"} {
+		if sel, err := ParseSelector(expr); err == nil {
+			t.Errorf("Expected an error for %q, got: %+v", expr, sel)
+		}
+	}
+	if _, err := ParseSelector("3-"); err == nil || !strings.Contains(err.Error(), `stray "-"`) {
+		t.Errorf("Expected a stray dash error for \"3-\", got: %v", err)
+	}
+	sel, err := ParseSelector("#abcd,ABCD-12")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(sel.IDs) != 2 {
+		t.Errorf("Expected two IDs, got: %+v", sel)
+	}
+
+	list := NewTaskList("pageID", []Block{todoBlock("3abc1111-0000-0000-0000-000000000000", "first")})
+	if _, err := list.ResolveRef("3-"); err == nil {
+		t.Errorf("Expected ResolveRef to reject \"3-\"")
+	}
+}
@@ -0,0 +1,147 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MinShortIDLength is the shortest ID prefix `list --ids` shows.
+const MinShortIDLength = 4
+
+// normalizeID strips the dashes and case from a block ID or prefix so both
+// "1A2B3C4D-..." and "1a2b3c4d..." compare equal.
+func normalizeID(id string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(id, "#"), "-", ""))
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkIDPrefix explains why s cannot be a typed block ID or prefix. A prefix
+// needs MinShortIDLength characters, so that a typo such as "3-" is not taken
+// for whichever task's ID happens to start with "3".
+func checkIDPrefix(s string) error {
+	trimmed := strings.TrimPrefix(s, "#")
+	if strings.HasPrefix(trimmed, "-") || strings.HasSuffix(trimmed, "-") {
+		return fmt.Errorf("%q has a stray \"-\"; give a task number, a range such as 5-8, or a task ID", s)
+	}
+	normalized := normalizeID(s)
+	if normalized == "" {
+		return fmt.Errorf("%q is neither a task number, a range nor a task ID", s)
+	}
+	for _, r := range normalized {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return fmt.Errorf("%q is neither a task number, a range nor a task ID", s)
+		}
+	}
+	if len(normalized) < MinShortIDLength {
+		return fmt.Errorf("%q is too short for a task ID; give at least %d characters", s, MinShortIDLength)
+	}
+	return nil
+}
+
+// ShortIDs returns, for every task in the list, the shortest prefix of its
+// block ID that is unique within the list. Prefixes are at least
+// MinShortIDLength characters and are extended until they contain a letter, so
+// a short ID can never be mistaken for a position number.
+func (l *TaskList) ShortIDs() map[*Task]string {
+	ids := make([]string, len(l.Tasks))
+	for i, task := range l.Tasks {
+		ids[i] = normalizeID(task.Block.ID)
+	}
+
+	shortIDs := make(map[*Task]string, len(l.Tasks))
+	for i, task := range l.Tasks {
+		id := ids[i]
+		length := MinShortIDLength
+		for ; length < len(id); length++ {
+			prefix := id[:length]
+			if isDigits(prefix) {
+				continue
+			}
+			unique := true
+			for j, other := range ids {
+				if j != i && strings.HasPrefix(other, prefix) {
+					unique = false
+					break
+				}
+			}
+			if unique {
+				break
+			}
+		}
+		if length > len(id) {
+			length = len(id)
+		}
+		shortIDs[task] = id[:length]
+	}
+	return shortIDs
+}
+
+// AmbiguousIDError is returned when an ID prefix matches several tasks.
+type AmbiguousIDError struct {
+	Prefix     string
+	Candidates []*Task
+}
+
+func (e *AmbiguousIDError) Error() string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "ID prefix %q matches %d tasks:", e.Prefix, len(e.Candidates))
+	for _, task := range e.Candidates {
+		fmt.Fprintf(&msg, "\n  %s %s %s", task.Number, normalizeID(task.Block.ID), task.Block.ToDo.PlainText())
+	}
+	return msg.String()
+}
+
+// ResolveID finds the task whose block ID starts with prefix.
+func (l *TaskList) ResolveID(prefix string) (*Task, error) {
+	if err := checkIDPrefix(prefix); err != nil {
+		return nil, err
+	}
+	normalized := normalizeID(prefix)
+	var candidates []*Task
+	for _, task := range l.Tasks {
+		if strings.HasPrefix(normalizeID(task.Block.ID), normalized) {
+			candidates = append(candidates, task)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("there is no task with ID %q", prefix)
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, &AmbiguousIDError{Prefix: prefix, Candidates: candidates}
+}
+
+// ResolveRef resolves a task reference as typed by the user: a number such
+// as "3" or "3.2" is a position from `list`, anything else is a block ID
+// prefix.
+func (l *TaskList) ResolveRef(ref string) (*Task, error) {
+	ref = strings.TrimSpace(ref)
+	if isDigits(ref) {
+		position, err := strconv.Atoi(ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert %q to a task number", ref)
+		}
+		return l.Resolve(position)
+	}
+	if numberPattern.MatchString(ref) {
+		return l.ResolveNumber(ref)
+	}
+	return l.ResolveID(ref)
+}
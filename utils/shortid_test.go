@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShortIDsAreUniqueAndNeverNumeric(t *testing.T) {
+	list := NewTaskList("pageID", []Block{
+		todoBlock("abcd1111-0000-0000-0000-000000000000", "first"),
+		todoBlock("abcd2222-0000-0000-0000-000000000000", "second"),
+		todoBlock("12345678-9abc-0000-0000-000000000000", "third"),
+	})
+
+	shortIDs := list.ShortIDs()
+	want := []string{"abcd1", "abcd2", "123456789a"}
+	for i, task := range list.Tasks {
+		if shortIDs[task] != want[i] {
+			t.Errorf("Expected short ID %q for task %d, got: %q", want[i], task.Position, shortIDs[task])
+		}
+		resolved, err := list.ResolveRef(shortIDs[task])
+		if err != nil || resolved != task {
+			t.Errorf("Expected %q to resolve to task %d, got: %v, %v", shortIDs[task], task.Position, resolved, err)
+		}
+	}
+}
+
+func TestResolveRefAcceptsPositionsAndIDPrefixes(t *testing.T) {
+	list := NewTaskList("pageID", []Block{
+		todoBlock("abcd1111-0000-0000-0000-000000000000", "first"),
+		todoBlock("abcd2222-0000-0000-0000-000000000000", "second"),
+	})
+
+	if task, err := list.ResolveRef("2"); err != nil || task.Block.ToDo.PlainText() != "second" {
+		t.Errorf("Expected position 2 to resolve to the second task, got: %v, %v", task, err)
+	}
+	if task, err := list.ResolveRef("#ABCD-2"); err != nil || task.Block.ToDo.PlainText() != "second" {
+		t.Errorf("Expected #ABCD-2 to resolve to the second task, got: %v, %v", task, err)
+	}
+	if _, err := list.ResolveRef("ffff"); err == nil {
+		t.Errorf("Expected an error for an unknown ID")
+	}
+
+	_, err := list.ResolveRef("abcd")
+	var ambiguous *AmbiguousIDError
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("Expected an ambiguous ID error with 2 candidates, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "first") || !strings.Contains(err.Error(), "second") {
+		t.Errorf("Expected the error to list both candidates, got: %v", err)
+	}
+}
+
+func TestParseSelectorMixesPositionsAndIDs(t *testing.T) {
+	sel, err := ParseSelector("1,3-4,abcd2")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(sel.Positions) != 3 || len(sel.IDs) != 1 || sel.IDs[0] != "abcd2" {
+		t.Errorf("Unexpected selector: %+v", sel)
+	}
+	if _, err := ParseSelector("1,not-an-id"); err == nil {
+		t.Errorf("Expected an error for a reference that is neither a number nor an ID")
+	}
+}
@@ -0,0 +1,58 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortKeys are the orders SortTasks understands.
+var SortKeys = []string{"edited", "created", "text", "status"}
+
+// SortTasks returns tasks in a new order: "edited" and "created" put the
+// most recent first, "text" sorts alphabetically ignoring case, and "status"
+// puts open tasks before done ones. reverse flips the order. Tasks that
+// compare equal keep their page order either way.
+func SortTasks(tasks []*Task, key string, reverse bool) ([]*Task, error) {
+	var less func(a, b *Task) bool
+	switch key {
+	case "edited":
+		less = func(a, b *Task) bool {
+			return blockTime(b.Block.LastEditedTime).Before(blockTime(a.Block.LastEditedTime))
+		}
+	case "created":
+		less = func(a, b *Task) bool {
+			return blockTime(b.Block.CreatedTime).Before(blockTime(a.Block.CreatedTime))
+		}
+	case "text":
+		less = func(a, b *Task) bool {
+			return strings.ToLower(a.Block.ToDo.PlainText()) < strings.ToLower(b.Block.ToDo.PlainText())
+		}
+	case "status":
+		less = func(a, b *Task) bool {
+			return !a.Block.ToDo.Checked && b.Block.ToDo.Checked
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort order %q; use one of %s", key, strings.Join(SortKeys, ", "))
+	}
+
+	sorted := append([]*Task(nil), tasks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if reverse {
+			return less(sorted[j], sorted[i])
+		}
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
+
+// blockTime parses a block timestamp, giving the zero time if it is missing.
+func blockTime(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339, timestamp)
+	return t
+}
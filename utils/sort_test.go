@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortTasks(t *testing.T) {
+	task := func(number, text string, checked bool, created, edited string) *Task {
+		return &Task{Number: number, Block: Block{
+			CreatedTime:    created,
+			LastEditedTime: edited,
+			ToDo:           &ToDo{Checked: checked, RichText: PlainRichText(text)},
+		}}
+	}
+	tasks := []*Task{
+		task("1", "write report", true, "2023-05-01T08:00:00.000Z", "2023-05-03T08:00:00.000Z"),
+		task("2", "Buy milk", false, "2023-05-02T08:00:00.000Z", "2023-05-02T08:00:00.000Z"),
+		task("3", "call mum", true, "2023-05-03T08:00:00.000Z", "2023-05-04T08:00:00.000Z"),
+		task("4", "answer email", false, "2023-05-03T08:00:00.000Z", "2023-05-01T08:00:00.000Z"),
+	}
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{"edited", false, []string{"3", "1", "2", "4"}},
+		{"edited", true, []string{"4", "2", "1", "3"}},
+		{"created", false, []string{"3", "4", "2", "1"}},
+		{"created", true, []string{"1", "2", "3", "4"}},
+		{"text", false, []string{"4", "2", "3", "1"}},
+		{"status", false, []string{"2", "4", "1", "3"}},
+		{"status", true, []string{"1", "3", "2", "4"}},
+	}
+	for _, test := range tests {
+		sorted, err := SortTasks(tasks, test.key, test.reverse)
+		if err != nil {
+			t.Fatalf("Got error: %v", err)
+		}
+		var got []string
+		for _, task := range sorted {
+			got = append(got, task.Number)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Sorting by %s (reverse %v): expected %v, got %v", test.key, test.reverse, test.want, got)
+		}
+	}
+	if tasks[0].Number != "1" {
+		t.Errorf("Expected the input to be left in page order")
+	}
+	if _, err := SortTasks(tasks, "priority", false); err == nil {
+		t.Errorf("Expected an error for an unknown sort order")
+	}
+}
@@ -0,0 +1,235 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Task is a to-do block together with the number `list` shows for it.
+// Subtasks are to-dos nested under another to-do; they are numbered within
+// their parent, so the second subtask of task 3 is "3.2". To-dos inside
+// toggles, columns and synced blocks are numbered as if the container were
+// not there, in document order, and Location says where they live.
+type Task struct {
+	Position int    // 1-based position among the task's siblings
+	Number   string // hierarchical number, e.g. "3" or "3.2"
+	Parent   *Task
+	Children []*Task
+	Block    Block
+	Location []string // containers from the page down, e.g. ["Errands", "column 2"]
+	Section  *Section // the section under the nearest heading above, if any
+}
+
+// Depth is 0 for top-level tasks, 1 for their subtasks and so on.
+func (t *Task) Depth() int {
+	depth := 0
+	for parent := t.Parent; parent != nil; parent = parent.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Descendants returns the task's subtasks, their subtasks and so on, parents
+// before their children.
+func (t *Task) Descendants() []*Task {
+	var descendants []*Task
+	for _, child := range t.Children {
+		descendants = append(descendants, child)
+		descendants = append(descendants, child.Descendants()...)
+	}
+	return descendants
+}
+
+// WithDescendants returns tasks followed by their descendants, each task
+// once, in document order.
+func WithDescendants(tasks []*Task) []*Task {
+	var all []*Task
+	seen := map[*Task]bool{}
+	for _, task := range tasks {
+		for _, t := range append([]*Task{task}, task.Descendants()...) {
+			if !seen[t] {
+				seen[t] = true
+				all = append(all, t)
+			}
+		}
+	}
+	return all
+}
+
+// TaskList is a snapshot of the tasks on a page. Listing and every mutation
+// resolve positions through it, so the number a user sees in `list` always
+// addresses the same block in check, uncheck and delete.
+type TaskList struct {
+	PageID   string
+	Roots    []*Task    // top-level tasks
+	Tasks    []*Task    // every task, each followed by its subtasks
+	Sections []*Section // in page order
+}
+
+// isTask reports whether a block is a to-do that `list` shows. To-dos
+// without any text are skipped, as there is nothing to display for them.
+func isTask(block Block) bool {
+	return block.Object == "block" && block.Type == TypeToDo && block.ToDo != nil && len(block.ToDo.RichText) > 0
+}
+
+// isTaskOrContainer reports whether a block's children may hold tasks.
+func isTaskOrContainer(block Block) bool {
+	return isTask(block) || block.IsContainer()
+}
+
+// NewTaskList numbers the tasks among a page's children, skipping headings,
+// paragraphs and any other non-task blocks. To-dos found in a task's
+// Children become its subtasks, and to-dos in the Children of containers
+// are found as well. A to-do that appears twice, as the content of a synced
+// block and of its duplicate, is listed once. Headings divide the tasks
+// into Sections.
+func NewTaskList(pageID string, children []Block) *TaskList {
+	list := &TaskList{PageID: pageID}
+	walk := &taskWalk{list: list, seen: map[string]bool{}}
+	walk.blocks(nil, children, pageID, nil, nil, &list.Roots)
+	return list
+}
+
+type taskWalk struct {
+	list *TaskList
+	seen map[string]bool
+}
+
+// blocks walks the children of parentID. Tasks are appended to siblings and
+// belong to section until a heading among the blocks starts a new one.
+func (w *taskWalk) blocks(parent *Task, blocks []Block, parentID string, location []string, section *Section, siblings *[]*Task) {
+	for _, block := range blocks {
+		switch {
+		case isTask(block):
+			if w.seen[block.ID] {
+				continue
+			}
+			w.seen[block.ID] = true
+			task := &Task{Position: len(*siblings) + 1, Parent: parent, Block: block, Location: location, Section: section}
+			task.Number = strconv.Itoa(task.Position)
+			if parent != nil {
+				task.Number = parent.Number + "." + task.Number
+			}
+			*siblings = append(*siblings, task)
+			w.list.Tasks = append(w.list.Tasks, task)
+			if section != nil {
+				section.add(task, parentID)
+			}
+			w.blocks(task, block.Children, block.ID, location, section, &task.Children)
+
+		case block.HeadingLevel() > 0:
+			section = &Section{Title: block.PlainText(), Heading: block, parentID: parentID}
+			if block.IsContainer() {
+				section.parentID = block.ID
+			}
+			w.list.Sections = append(w.list.Sections, section)
+			w.blocks(parent, block.Children, block.ID, location, section, siblings)
+
+		case block.Type == TypeColumnList:
+			for i, column := range block.Children {
+				w.blocks(parent, column.Children, column.ID, within(location, fmt.Sprintf("column %d", i+1)), section, siblings)
+			}
+
+		case block.IsContainer():
+			w.blocks(parent, block.Children, block.ChildrenID(), within(location, containerLabel(block)), section, siblings)
+		}
+	}
+}
+
+// within returns location extended by one more container, without sharing
+// memory with location.
+func within(location []string, label string) []string {
+	return append(append([]string(nil), location...), label)
+}
+
+func containerLabel(block Block) string {
+	if block.Type == TypeSyncedBlock {
+		return "synced block"
+	}
+	if text := block.PlainText(); text != "" {
+		return text
+	}
+	return block.Type
+}
+
+// GetTaskList fetches a page's tasks, their subtasks and the tasks inside
+// containers, and numbers them.
+func (c *NotionClient) GetTaskList(ctx context.Context, pageID string) (*TaskList, error) {
+	children, err := c.GetTaskTree(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	return NewTaskList(pageID, children), nil
+}
+
+// GetTaskTree fetches the blocks GetTaskList numbers: the page's children,
+// with the Children of tasks and containers filled in.
+func (c *NotionClient) GetTaskTree(ctx context.Context, pageID string) ([]Block, error) {
+	return c.GetBlockTree(ctx, pageID, isTaskOrContainer)
+}
+
+// Resolve turns a displayed top-level position into its task.
+func (l *TaskList) Resolve(position int) (*Task, error) {
+	if position < 1 {
+		return nil, fmt.Errorf("task number must be greater than 0")
+	}
+	if position > len(l.Roots) {
+		return nil, fmt.Errorf("there is no task %d, the list has %d tasks", position, len(l.Roots))
+	}
+	return l.Roots[position-1], nil
+}
+
+// ResolveNumber turns a hierarchical number such as "3.2" into its task.
+func (l *TaskList) ResolveNumber(number string) (*Task, error) {
+	for _, task := range l.Tasks {
+		if task.Number == number {
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("there is no task %s", number)
+}
+
+// MoveTask moves a task, with its subtasks, so that it becomes number
+// position among its siblings: the page's top-level tasks, or the other
+// subtasks of its parent. The moved task gets a new ID, see MoveBlock.
+func (c *NotionClient) MoveTask(ctx context.Context, list *TaskList, task *Task, position int) (*Block, error) {
+	siblings := list.Roots
+	if task.Parent != nil {
+		siblings = task.Parent.Children
+	}
+	if position < 1 || position > len(siblings) {
+		return nil, fmt.Errorf("cannot move task %s to position %d, there are %d positions", task.Number, position, len(siblings))
+	}
+	if position == task.Position {
+		return &task.Block, nil
+	}
+
+	var others []*Task
+	for _, sibling := range siblings {
+		if sibling != task {
+			others = append(others, sibling)
+		}
+	}
+	if position == 1 {
+		return c.MoveBlockBefore(ctx, task.Block, others[0].Block)
+	}
+	after := others[position-2].Block
+	parentID := after.ParentID()
+	if parentID == "" {
+		parentID = list.PageID
+	}
+	return c.MoveBlock(ctx, task.Block, parentID, after.ID)
+}
+
+// requireToDo guards mutations that only make sense on to-do blocks.
+func requireToDo(block Block) error {
+	if block.Type != TypeToDo {
+		return fmt.Errorf("block %s is a %s, not a to-do", block.ID, block.Type)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func headingBlock(id string) Block {
+	return Block{Object: "block", ID: id, Type: "heading_2"}
+}
+
+func todoBlock(id, text string) Block {
+	block := mockBlock([]string{text})
+	block.ID = id
+	block.LastEditedTime = "2023-05-01T10:00:00.000Z"
+	return block
+}
+
+// newMixedPageServer serves a page where tasks are interleaved with headings
+// and an empty to-do, and records which blocks were patched.
+func newMixedPageServer(t *testing.T, patched *[]string) *httptest.Server {
+	children := []Block{
+		headingBlock("heading"),
+		todoBlock("first", "first task"),
+		mockBlock(nil),
+		headingBlock("another heading"),
+		todoBlock("second", "second task"),
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/blocks/pageID/children":
+			if err := json.NewEncoder(w).Encode(BlockList{Results: children}); err != nil {
+				t.Errorf("Error encoding response: %v", err)
+			}
+		case r.Method == http.MethodPatch:
+			*patched = append(*patched, r.URL.Path)
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestListAndMutationsAgreeOnPositions(t *testing.T) {
+	var patched []string
+	server := newMixedPageServer(t, &patched)
+	defer server.Close()
+	client := NewNotionClient("fakeKey", WithBaseURL(server.URL))
+	ctx := context.Background()
+
+	listed, err := client.GetToDoBlocks(ctx, "pageID", time.UTC)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(listed) != 2 || listed[1] != "2 [ ] second task (2023-05-01 10:00)" {
+		t.Fatalf("Unexpected listing: %q", listed)
+	}
+
+	if err := client.MarkToDoBlockChecked(ctx, "pageID", 2); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if len(patched) != 1 || patched[0] != "/blocks/second" {
+		t.Errorf("Expected task 2 to patch /blocks/second, got: %v", patched)
+	}
+}
+
+func TestResolveRejectsOutOfRangePositions(t *testing.T) {
+	list := NewTaskList("pageID", []Block{headingBlock("heading"), todoBlock("first", "first task")})
+	if _, err := list.Resolve(2); err == nil {
+		t.Errorf("Expected an error for a position past the last task")
+	}
+	if _, err := list.Resolve(0); err == nil {
+		t.Errorf("Expected an error for position 0")
+	}
+}
+
+func TestMutationsRefuseNonToDoBlocks(t *testing.T) {
+	client := NewNotionClient("fakeKey", WithBaseURL("http://127.0.0.1:0"))
+	if _, err := client.SetToDoChecked(context.Background(), headingBlock("heading"), true); err == nil {
+		t.Errorf("Expected an error when checking a heading")
+	}
+}
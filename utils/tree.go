@@ -0,0 +1,75 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// GetBlockTree fetches the children of blockID and, for every child with
+// children of its own that descend accepts, fetches those into its Children,
+// recursively. The content of a duplicate synced block is fetched from its
+// original. Sibling subtrees are fetched concurrently, with at most
+// DefaultConcurrency requests in flight.
+func (c *NotionClient) GetBlockTree(ctx context.Context, blockID string, descend func(Block) bool) ([]Block, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	fetch := &treeFetch{client: c, descend: descend, sem: make(chan struct{}, DefaultConcurrency), cancel: cancel}
+	children := fetch.children(ctx, blockID)
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	return children, nil
+}
+
+type treeFetch struct {
+	client  *NotionClient
+	descend func(Block) bool
+	sem     chan struct{}
+	cancel  context.CancelFunc
+
+	once sync.Once
+	err  error
+}
+
+// fail records the first error and stops the rest of the fetch.
+func (f *treeFetch) fail(err error) {
+	f.once.Do(func() {
+		f.err = err
+		f.cancel()
+	})
+}
+
+// children holds a slot of sem only while fetching blockID's own children,
+// never while waiting for its subtrees, so deep trees cannot deadlock.
+func (f *treeFetch) children(ctx context.Context, blockID string) []Block {
+	select {
+	case f.sem <- struct{}{}:
+	case <-ctx.Done():
+		f.fail(ctx.Err())
+		return nil
+	}
+	children, err := f.client.GetAllBlockChildren(ctx, blockID)
+	<-f.sem
+	if err != nil {
+		f.fail(err)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := range children {
+		if !(children[i].HasChildren || children[i].ChildrenID() != children[i].ID) || !f.descend(children[i]) {
+			continue
+		}
+		wg.Add(1)
+		go func(child *Block) {
+			defer wg.Done()
+			child.Children = f.children(ctx, child.ChildrenID())
+		}(&children[i])
+	}
+	wg.Wait()
+	return children
+}
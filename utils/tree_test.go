@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"notioncli/notiontest"
+)
+
+func TestGetTaskListNumbersSubtasks(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	server.AppendBlocks(pageID,
+		notiontest.ToDo("plan", false),
+		notiontest.ToDo("launch", false,
+			notiontest.ToDo("write post", false),
+			notiontest.Paragraph("not a task"),
+			notiontest.ToDo("deploy", false,
+				notiontest.ToDo("staging", true),
+				notiontest.ToDo("production", false),
+			),
+		),
+	)
+	client := newFakeClient(server)
+
+	list, err := client.GetTaskList(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	var numbers []string
+	for _, task := range list.Tasks {
+		numbers = append(numbers, task.Number+" "+task.Block.ToDo.PlainText())
+	}
+	want := []string{"1 plan", "2 launch", "2.1 write post", "2.2 deploy", "2.2.1 staging", "2.2.2 production"}
+	if !reflect.DeepEqual(numbers, want) {
+		t.Errorf("Expected %v, got: %v", want, numbers)
+	}
+
+	deploy, err := list.ResolveRef("2.2")
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	if deploy.Depth() != 1 || deploy.Parent != list.Roots[1] || len(deploy.Descendants()) != 2 {
+		t.Errorf("Expected 2.2 to be a subtask of 2 with two subtasks, got depth %d", deploy.Depth())
+	}
+	if all := WithDescendants([]*Task{list.Roots[1], deploy}); len(all) != 5 {
+		t.Errorf("Expected task 2 and its 4 descendants once each, got: %d", len(all))
+	}
+}
+
+func TestGetTaskListWalksContainers(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID,
+		notiontest.ToDo("first", false),
+		notiontest.Toggle("Errands", notiontest.ToDo("buy milk", false)),
+		notiontest.Block("column_list", nil,
+			notiontest.Block("column", nil, notiontest.ToDo("left", false)),
+			notiontest.Block("column", nil, notiontest.ToDo("right", false, notiontest.ToDo("right sub", false))),
+		),
+		notiontest.Block("synced_block", map[string]interface{}{"synced_from": nil}, notiontest.ToDo("shared", false)),
+		notiontest.Paragraph("Shared again below"),
+	)
+	server.AppendBlocks(pageID,
+		notiontest.Block("synced_block", map[string]interface{}{"synced_from": map[string]interface{}{"type": "block_id", "block_id": ids[3]}}),
+		notiontest.Block("heading_2", map[string]interface{}{
+			"rich_text":     []interface{}{map[string]interface{}{"type": "text", "text": map[string]interface{}{"content": "Later"}}},
+			"is_toggleable": true,
+		}, notiontest.ToDo("someday", false)),
+	)
+	client := newFakeClient(server)
+
+	list, err := client.GetTaskList(context.Background(), pageID)
+	if err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	var got []string
+	for _, task := range list.Tasks {
+		got = append(got, fmt.Sprintf("%s %s %v", task.Number, task.Block.ToDo.PlainText(), task.Location))
+	}
+	want := []string{
+		"1 first []",
+		"2 buy milk [Errands]",
+		"3 left [column 1]",
+		"4 right [column 2]",
+		"4.1 right sub [column 2]",
+		"5 shared [synced block]",
+		"6 someday []",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got: %v", want, got)
+	}
+	if section := list.Tasks[6].Section; section == nil || section.Title != "Later" {
+		t.Errorf("Expected the task in the toggleable heading to be in its section, got: %v", section)
+	}
+}
+
+func TestGetBlockTreeStopsOnError(t *testing.T) {
+	server := notiontest.NewServer()
+	defer server.Close()
+	pageID := server.AddPage("Tasks")
+	ids := server.AppendBlocks(pageID, notiontest.ToDo("parent", false, notiontest.ToDo("child", false)))
+	server.Fail(notiontest.Failure{Method: "GET", Path: "/blocks/" + ids[0] + "/children", Status: 404, Code: "object_not_found", Times: 1})
+
+	if _, err := newFakeClient(server).GetBlockTree(context.Background(), pageID, isTask); err == nil {
+		t.Errorf("Expected the failing subtree to fail the whole fetch")
+	}
+}
@@ -0,0 +1,246 @@
+// This code is licensed under the Apache License, Version 2.0 (the "License").
+// You may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://www.apache.org/licenses/LICENSE-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ViewEntry records one task as `list` displayed it.
+type ViewEntry struct {
+	Position       int    `json:"position"`
+	Number         string `json:"number,omitempty"`
+	ID             string `json:"id"`
+	Text           string `json:"text"`
+	LastEditedTime string `json:"last_edited_time"`
+	Deleted        bool   `json:"deleted,omitempty"`
+}
+
+// View is the snapshot of a page that `list` last displayed. Destructive
+// commands compare their targets against it, so that a task number typed
+// from an old listing cannot silently hit a different task.
+type View struct {
+	PageID   string      `json:"page_id"`
+	ListedAt time.Time   `json:"listed_at"`
+	Tasks    []ViewEntry `json:"tasks"`
+}
+
+// StaleViewError is returned when a target no longer matches what `list`
+// showed for it.
+type StaleViewError struct {
+	Ref    string
+	Reason string
+}
+
+func (e *StaleViewError) Error() string {
+	return fmt.Sprintf("page changed since you listed it: task %s %s", e.Ref, e.Reason)
+}
+
+// NewView records the tasks `list` is about to display. Only the tasks
+// shown count: a number that --section or a filter hid was never seen, so a
+// later command using it is refused.
+func NewView(pageID string, tasks []*Task) *View {
+	view := &View{PageID: pageID, ListedAt: time.Now()}
+	for _, task := range tasks {
+		view.Tasks = append(view.Tasks, ViewEntry{
+			Position:       task.Position,
+			Number:         task.Number,
+			ID:             task.Block.ID,
+			Text:           task.Block.ToDo.PlainText(),
+			LastEditedTime: task.Block.LastEditedTime,
+		})
+	}
+	return view
+}
+
+// ViewPath returns where the view of a page is stored, under the user's
+// cache directory.
+func ViewPath(pageID string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "notioncli", "views", normalizeID(pageID)+".json"), nil
+}
+
+// LoadView reads a saved view. A missing file is not an error: it returns a
+// nil view, which accepts every target.
+func LoadView(path string) (*View, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var view View
+	if err := json.Unmarshal(data, &view); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return &view, nil
+}
+
+// Save writes the view to path, creating its directory if needed.
+func (v *View) Save(path string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0o600)
+}
+
+func (v *View) entryByID(id string) *ViewEntry {
+	for i := range v.Tasks {
+		if v.Tasks[i].ID == id {
+			return &v.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// entryByNumber finds the entry listed as number. Views saved before
+// subtasks were numbered only have top-level positions.
+func (v *View) entryByNumber(number string) *ViewEntry {
+	for i := range v.Tasks {
+		entry := &v.Tasks[i]
+		if entry.Number == number || entry.Number == "" && strconv.Itoa(entry.Position) == number {
+			return entry
+		}
+	}
+	return nil
+}
+
+// checkUnchanged reports a task that was edited after it was listed.
+func (v *View) checkUnchanged(ref string, task *Task) error {
+	entry := v.entryByID(task.Block.ID)
+	if entry == nil {
+		return nil
+	}
+	if text := task.Block.ToDo.PlainText(); text != entry.Text {
+		return &StaleViewError{Ref: ref, Reason: fmt.Sprintf("was %q when listed and is now %q", entry.Text, text)}
+	}
+	if task.Block.LastEditedTime != entry.LastEditedTime {
+		return &StaleViewError{Ref: ref, Reason: fmt.Sprintf("(%q) was edited after it was listed", entry.Text)}
+	}
+	return nil
+}
+
+// CheckPosition verifies that the task now at a top-level position is the
+// one `list` showed there, unchanged.
+func (v *View) CheckPosition(position int, task *Task) error {
+	return v.CheckNumber(strconv.Itoa(position), task)
+}
+
+// CheckNumber is CheckPosition for any task number, including subtasks such
+// as "3.2".
+func (v *View) CheckNumber(number string, task *Task) error {
+	if v == nil {
+		return nil
+	}
+	ref := number
+	entry := v.entryByNumber(number)
+	if entry == nil {
+		return &StaleViewError{Ref: ref, Reason: "was not shown when you listed the page"}
+	}
+	if entry.Deleted {
+		return &StaleViewError{Ref: ref, Reason: fmt.Sprintf("(%q) has been deleted and the tasks after it have moved up", entry.Text)}
+	}
+	if entry.ID != task.Block.ID {
+		return &StaleViewError{Ref: ref, Reason: fmt.Sprintf("was %q when listed and is now %q", entry.Text, task.Block.ToDo.PlainText())}
+	}
+	return v.checkUnchanged(ref, task)
+}
+
+// CheckID verifies that a task addressed by ID has not been edited since it
+// was listed. Tasks added after the listing are accepted, as their ID could
+// only have come from somewhere other than the stale view.
+func (v *View) CheckID(id string, task *Task) error {
+	if v == nil {
+		return nil
+	}
+	return v.checkUnchanged(id, task)
+}
+
+// CheckSelection verifies every task a selector names explicitly. Tasks
+// picked by --all, --done, --open or --match are chosen by their current
+// state, so there is nothing stale about them.
+func (v *View) CheckSelection(list *TaskList, sel Selector) error {
+	if v == nil {
+		return nil
+	}
+	for _, position := range sel.Positions {
+		task, err := list.Resolve(position)
+		if err != nil {
+			return err
+		}
+		if err := v.CheckPosition(position, task); err != nil {
+			return err
+		}
+	}
+	for _, number := range sel.Numbers {
+		task, err := list.ResolveNumber(number)
+		if err != nil {
+			return err
+		}
+		if err := v.CheckNumber(number, task); err != nil {
+			return err
+		}
+	}
+	for _, id := range sel.IDs {
+		task, err := list.ResolveID(id)
+		if err != nil {
+			return err
+		}
+		if err := v.CheckID(id, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckDescendants verifies the subtasks of tasks, which --recursive and
+// delete act on along with the tasks themselves. Each must be one `list`
+// showed, unchanged, so that a subtask added or edited since is not hit
+// unseen.
+func (v *View) CheckDescendants(tasks []*Task) error {
+	if v == nil {
+		return nil
+	}
+	for _, task := range tasks {
+		for _, subtask := range task.Descendants() {
+			if err := v.CheckNumber(subtask.Number, subtask); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Record refreshes a task's entry after this process changed it, so our own
+// edits do not make the view look stale.
+func (v *View) Record(task *Task) {
+	if entry := v.entryByID(task.Block.ID); entry != nil {
+		entry.Text = task.Block.ToDo.PlainText()
+		entry.LastEditedTime = task.Block.LastEditedTime
+	}
+}
+
+// MarkDeleted records that this process deleted a task. Later tasks have
+// moved up a position, so a command using the deleted task's number is
+// refused until the page is listed again.
+func (v *View) MarkDeleted(id string) {
+	if entry := v.entryByID(id); entry != nil {
+		entry.Deleted = true
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestViewDetectsReorderedAndEditedTasks(t *testing.T) {
+	listed := NewTaskList("pageID", []Block{
+		todoBlock("aaaa1111", "first task"),
+		todoBlock("bbbb2222", "second task"),
+		todoBlock("cccc3333", "third task"),
+	})
+	path := filepath.Join(t.TempDir(), "view.json")
+	if err := NewView(listed.PageID, listed.Tasks).Save(path); err != nil {
+		t.Fatalf("Got error: %v", err)
+	}
+	view, err := LoadView(path)
+	if err != nil || view == nil {
+		t.Fatalf("Expected to load the saved view, got: %v, %v", view, err)
+	}
+
+	edited := todoBlock("cccc3333", "third task, reworded")
+	current := NewTaskList("pageID", []Block{
+		todoBlock("bbbb2222", "second task"),
+		todoBlock("aaaa1111", "first task"),
+		edited,
+	})
+
+	var staleErr *StaleViewError
+	if err := view.CheckSelection(current, Selector{Positions: []int{1}}); !errors.As(err, &staleErr) {
+		t.Errorf("Expected a reordered task to be stale, got: %v", err)
+	}
+	if err := view.CheckSelection(current, Selector{IDs: []string{"aaaa"}}); err != nil {
+		t.Errorf("Expected an unchanged task addressed by ID to pass, got: %v", err)
+	}
+	if err := view.CheckSelection(current, Selector{Positions: []int{3}}); !errors.As(err, &staleErr) {
+		t.Errorf("Expected an edited task to be stale, got: %v", err)
+	}
+	if err := view.CheckSelection(current, Selector{All: true}); err != nil {
+		t.Errorf("Expected filter selections to pass, got: %v", err)
+	}
+}
+
+func TestViewTracksOwnChanges(t *testing.T) {
+	list := NewTaskList("pageID", []Block{
+		todoBlock("aaaa1111", "first task"),
+		todoBlock("bbbb2222", "second task"),
+		todoBlock("cccc3333", "third task"),
+	})
+	view := NewView(list.PageID, list.Tasks)
+
+	checked := list.Tasks[0]
+	checked.Block.ToDo.Checked = true
+	checked.Block.LastEditedTime = "2023-05-02T10:00:00.000Z"
+	view.Record(checked)
+	if err := view.CheckPosition(1, checked); err != nil {
+		t.Errorf("Expected our own edit to keep the view fresh, got: %v", err)
+	}
+
+	view.MarkDeleted("bbbb2222")
+	shifted := NewTaskList("pageID", []Block{checked.Block, todoBlock("cccc3333", "third task")})
+	if err := view.CheckSelection(shifted, Selector{Positions: []int{2}}); err == nil {
+		t.Errorf("Expected a position shifted by our own delete to be refused")
+	}
+}
+
+func TestMissingViewAcceptsEverything(t *testing.T) {
+	view, err := LoadView(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || view != nil {
+		t.Fatalf("Expected no view and no error, got: %v, %v", view, err)
+	}
+	list := NewTaskList("pageID", []Block{todoBlock("aaaa1111", "first task")})
+	if err := view.CheckSelection(list, Selector{Positions: []int{1}}); err != nil {
+		t.Errorf("Expected a missing view to accept every target, got: %v", err)
+	}
+}